@@ -52,18 +52,6 @@ class TradeClusterResponse(BaseModel):
     trade_cluster_map: Dict[int, int] = Field(..., description="Mapping of Trade ID to Cluster ID")
     cluster_summaries: List[ClusterInfo] = Field(..., description="Summary statistics for each cluster")
 
-class StrategyPerformanceMetrics(BaseModel):
-    """The performance metrics calculated for each trading strategy."""
-    total_pnl: float
-    win_rate: float
-    profit_factor: Optional[float] = None # can be None if no losses or no profits
-    trade_count: int
-
-class StrategyEffectivenessResponse(BaseModel):
-    """Response containing performance metrics keyed by strategy tag."""
-    strategy_performance: Dict[str, StrategyPerformanceMetrics]
-
-
 class MarketConditionPerformanceMetrics(BaseModel):
     """Performance metrics for previous day market condition."""
     total_pnl: float
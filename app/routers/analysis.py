@@ -3,13 +3,12 @@ from typing import List
 
 # Import models and services
 from app.models.trade_models import (
-    Trade, TimePatternResponse, TradeClusterResponse, StrategyEffectivenessResponse,
+    Trade, TimePatternResponse, TradeClusterResponse,
     MarketCorrelationResponse # Import new model
 )
 from app.services.analysis_service import (
     time_pattern_analysis,
     trade_clustering,
-    strategy_effectiveness_analysis,
     market_correlation_analysis
 )
 
@@ -61,23 +60,6 @@ async def analyze_trade_clusters_endpoint(
         raise HTTPException(status_code=500, detail="Internal server error during trade clustering.")
 
 
-@router.post("/strategy_effectiveness", response_model=StrategyEffectivenessResponse)
-async def analyze_strategy_effectiveness_endpoint(trades: List[Trade] = Body(...)):
-    """
-    Analyzes performance metrics for trades grouped by their strategy_tag.
-    Trades without a tag are grouped under 'Untagged'.
-    """
-    if not trades:
-        return StrategyEffectivenessResponse(strategy_performance={})
-
-    try:
-        result = strategy_effectiveness_analysis(trades)
-        return {"strategy_performance": result}
-    except Exception as e:
-        print(f"Error in strategy_effectiveness endpoint: {e}")
-        raise HTTPException(status_code=500, detail="Internal server error during strategy effectiveness analysis.")
-
-
 @router.post("/market_correlation", response_model=MarketCorrelationResponse)
 async def analyze_market_correlation_endpoint(trades: List[Trade] = Body(...)):
     """
@@ -14,7 +14,7 @@ from sklearn.preprocessing import StandardScaler
 from sklearn.impute import SimpleImputer
 
 from app.models.trade_models import (
-    Tag, Trade, TimePerformanceMetrics, ClusterInfo, StrategyPerformanceMetrics,
+    Tag, Trade, TimePerformanceMetrics, ClusterInfo,
     MarketConditionPerformanceMetrics
 )
 
@@ -182,45 +182,6 @@ def trade_clustering(trades: List[Trade], n_clusters: int, features: List[str])
     }
 
 
-def _calculate_profit_factor(group):
-    """Helper to calculate profit factor, also factoring in no loss trades"""
-    total_profit = group[group['pnl'] > 0]['pnl'].sum()
-    total_loss = abs(group[group['pnl'] < 0]['pnl'].sum())
-    if total_loss == 0:
-        return float('inf') if total_profit > 0 else None
-    return round(total_profit / total_loss, 2)
-
-def strategy_effectiveness_analysis(trades: List[Trade]) -> Dict[str, StrategyPerformanceMetrics]:
-    """
-    Analyzes performance metrics for trades grouped by their strategy_tag.
-    """
-    if not trades:
-        return {}
-
-    df = pd.DataFrame([trade.model_dump() for trade in trades])
-
-    # fill missing values in the strategy_tag column with "Untagged"
-    df['strategy_tag'] = df['strategy_tag'].fillna('Untagged')
-    # put true for all winning trades
-    df['is_win'] = df['pnl'] > 0
-
-    grouped = df.groupby('strategy_tag')
-
-    strategy_performance = {}
-    for name, group in grouped:
-        if len(group) == 0: continue
-        # calculate performance metrics for each strategy tag group
-        metrics = StrategyPerformanceMetrics(
-            total_pnl=round(group['pnl'].sum(), 2),
-            win_rate=round(group['is_win'].mean(), 2),
-            profit_factor=_calculate_profit_factor(group),
-            trade_count=len(group)
-        )
-        strategy_performance[name] = metrics
-
-    return strategy_performance
-
-
 def market_correlation_analysis(trades: List[Trade]) -> Dict[str, MarketConditionPerformanceMetrics]:
     """
     Analyzes trade performance based on previous day's market conditions using yfinance
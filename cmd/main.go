@@ -46,9 +46,20 @@ func main() {
 	// middleware for logging and recovering from panics
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	authHandlers := handlers.NewAuthHandlers(db)
 	tradeHandlers := handlers.NewTradeHandlers(db)
 	tagHandlers := handlers.NewTagHandlers(db)
 	statisticsHandlers := handlers.NewStatisticsHandlers(db)
+	importHandlers := handlers.NewImportHandlers(db)
+	executionHandlers := handlers.NewExecutionHandlers(db)
+	accountHandlers := handlers.NewAccountHandlers(db)
+	instrumentHandlers := handlers.NewInstrumentHandlers(db)
+	fxHandlers := handlers.NewFXHandlers(db)
+	positionHandlers := handlers.NewPositionHandlers(db)
+	tradePlanHandlers := handlers.NewTradePlanHandlers(db)
+	journalHandlers := handlers.NewJournalHandlers(db)
+	strategyHandlers := handlers.NewStrategyHandlers(db)
+	checklistHandlers := handlers.NewChecklistHandlers(db)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -59,24 +70,114 @@ func main() {
 	}))
 	// define the routes
 	r.Route("/api", func(r chi.Router) {
-		r.Get("/trades", tradeHandlers.ListTradesHandler)
-		r.Post("/trades", tradeHandlers.AddTradeHandler)
-		r.Get("/trades/{id}", tradeHandlers.GetTradeHandler)
-		r.Put("/trades/{id}", tradeHandlers.UpdateTradeHandler)
-		r.Delete("/trades/{id}", tradeHandlers.DeleteTradeHandler)
-
-		r.Get("/tags", tagHandlers.ListTagsHandler)
-		r.Post("/tags", tagHandlers.CreateTagHandler)
-		r.Get("/tags/{id}", tagHandlers.GetTagHandler)
-		r.Put("/tags/{id}", tagHandlers.UpdateTagHandler)
-		r.Delete("/tags/{id}", tagHandlers.DeleteTagHandler)
-
-		r.Get("/trades/{trade_id}/tags", tagHandlers.GetTradeTagsHandler)
-		r.Post("/trades/{trade_id}/tags/{tag_id}", tagHandlers.AddTagToTradeHandler)
-		r.Delete("/trades/{trade_id}/tags/{tag_id}", tagHandlers.RemoveTagFromTradeHandler)
-
-		r.Get("/statistics", statisticsHandlers.GetStatisticsHandler)
-	})	
+		r.Post("/auth/register", authHandlers.RegisterHandler)
+		r.Post("/auth/login", authHandlers.LoginHandler)
+		r.Post("/auth/logout", authHandlers.LogoutHandler)
+
+		// everything else needs a logged in user
+		r.Group(func(r chi.Router) {
+			r.Use(authHandlers.RequireAuth)
+
+			r.Get("/auth/me", authHandlers.MeHandler)
+			r.Put("/auth/me", authHandlers.UpdateMeHandler)
+
+			r.Get("/accounts", accountHandlers.ListAccountsHandler)
+			r.Post("/accounts", accountHandlers.CreateAccountHandler)
+			r.Get("/accounts/{id}", accountHandlers.GetAccountHandler)
+			r.Put("/accounts/{id}", accountHandlers.UpdateAccountHandler)
+			r.Delete("/accounts/{id}", accountHandlers.DeleteAccountHandler)
+			r.Get("/accounts/{id}/ledger", accountHandlers.ListLedgerEntriesHandler)
+			r.Post("/accounts/{id}/ledger", accountHandlers.AddLedgerEntryHandler)
+			r.Delete("/accounts/{id}/ledger/{entry_id}", accountHandlers.DeleteLedgerEntryHandler)
+
+			r.Get("/instruments", instrumentHandlers.ListInstrumentsHandler)
+			r.Post("/instruments", instrumentHandlers.CreateInstrumentHandler)
+			r.Get("/instruments/{id}", instrumentHandlers.GetInstrumentHandler)
+			r.Put("/instruments/{id}", instrumentHandlers.UpdateInstrumentHandler)
+			r.Delete("/instruments/{id}", instrumentHandlers.DeleteInstrumentHandler)
+
+			r.Get("/fx-rates", fxHandlers.ListFXRatesHandler)
+			r.Post("/fx-rates", fxHandlers.SaveFXRateHandler)
+			r.Post("/fx-rates/import", fxHandlers.ImportFXRatesHandler)
+			r.Delete("/fx-rates/{id}", fxHandlers.DeleteFXRateHandler)
+
+			r.Get("/trades", tradeHandlers.ListTradesHandler)
+			r.Post("/trades", tradeHandlers.AddTradeHandler)
+			r.Get("/trades/{id}", tradeHandlers.GetTradeHandler)
+			r.Put("/trades/{id}", tradeHandlers.UpdateTradeHandler)
+			r.Delete("/trades/{id}", tradeHandlers.DeleteTradeHandler)
+			r.Post("/trades/{id}/close", tradeHandlers.CloseTradeHandler)
+
+			r.Get("/trade-plans", tradePlanHandlers.ListTradePlansHandler)
+			r.Post("/trade-plans", tradePlanHandlers.CreateTradePlanHandler)
+			r.Get("/trade-plans/report", tradePlanHandlers.GetPlanReportHandler)
+			r.Get("/trade-plans/{id}", tradePlanHandlers.GetTradePlanHandler)
+			r.Put("/trade-plans/{id}", tradePlanHandlers.UpdateTradePlanHandler)
+			r.Delete("/trade-plans/{id}", tradePlanHandlers.DeleteTradePlanHandler)
+			r.Get("/trade-plans/{id}/comparison", tradePlanHandlers.CompareTradePlanHandler)
+			r.Post("/trade-plans/{id}/trade/{trade_id}", tradePlanHandlers.LinkTradePlanHandler)
+			r.Delete("/trade-plans/{id}/trade", tradePlanHandlers.UnlinkTradePlanHandler)
+
+			r.Get("/journal", journalHandlers.ListJournalDaysHandler)
+			r.Get("/journal/correlation", journalHandlers.GetJournalCorrelationHandler)
+			r.Get("/journal/{date}", journalHandlers.GetJournalDayHandler)
+			r.Put("/journal/{date}", journalHandlers.SaveJournalDayHandler)
+			r.Delete("/journal/{date}", journalHandlers.DeleteJournalDayHandler)
+
+			r.Get("/strategies", strategyHandlers.ListStrategiesHandler)
+			r.Post("/strategies", strategyHandlers.CreateStrategyHandler)
+			r.Get("/strategies/{id}", strategyHandlers.GetStrategyHandler)
+			r.Put("/strategies/{id}", strategyHandlers.UpdateStrategyHandler)
+			r.Delete("/strategies/{id}", strategyHandlers.DeleteStrategyHandler)
+			r.Get("/strategies/{id}/checklist", checklistHandlers.ListChecklistItemsHandler)
+			r.Post("/strategies/{id}/checklist", checklistHandlers.CreateChecklistItemHandler)
+			r.Put("/strategies/{id}/checklist/{item_id}", checklistHandlers.UpdateChecklistItemHandler)
+			r.Delete("/strategies/{id}/checklist/{item_id}", checklistHandlers.DeleteChecklistItemHandler)
+
+			r.Get("/trades/{trade_id}/checklist", checklistHandlers.GetTradeChecklistHandler)
+			r.Put("/trades/{trade_id}/checklist/{item_id}", checklistHandlers.AnswerChecklistItemHandler)
+			r.Delete("/trades/{trade_id}/checklist/{item_id}", checklistHandlers.RemoveChecklistAnswerHandler)
+
+			r.Get("/positions", positionHandlers.ListOpenPositionsHandler)
+			r.Get("/mark-prices", positionHandlers.ListMarkPricesHandler)
+			r.Put("/mark-prices", positionHandlers.SaveMarkPriceHandler)
+			r.Delete("/mark-prices/{id}", positionHandlers.DeleteMarkPriceHandler)
+
+			r.Get("/tags", tagHandlers.ListTagsHandler)
+			r.Post("/tags", tagHandlers.CreateTagHandler)
+			r.Get("/tags/{id}", tagHandlers.GetTagHandler)
+			r.Put("/tags/{id}", tagHandlers.UpdateTagHandler)
+			r.Delete("/tags/{id}", tagHandlers.DeleteTagHandler)
+
+			r.Get("/trades/{trade_id}/tags", tagHandlers.GetTradeTagsHandler)
+			r.Post("/trades/{trade_id}/tags/{tag_id}", tagHandlers.AddTagToTradeHandler)
+			r.Delete("/trades/{trade_id}/tags/{tag_id}", tagHandlers.RemoveTagFromTradeHandler)
+
+			r.Get("/executions", executionHandlers.ListExecutionsHandler)
+			r.Post("/executions", executionHandlers.AddExecutionsHandler)
+			r.Delete("/executions/{id}", executionHandlers.DeleteExecutionHandler)
+			r.Post("/executions/match", executionHandlers.MatchExecutionsHandler)
+			r.Get("/trades/{trade_id}/executions", executionHandlers.GetTradeExecutionsHandler)
+
+			r.Get("/statistics", statisticsHandlers.GetStatisticsHandler)
+			r.Get("/statistics/accounts", statisticsHandlers.GetAccountComparisonHandler)
+			r.Get("/statistics/equity", statisticsHandlers.GetEquityCurveHandler)
+			r.Get("/statistics/time", statisticsHandlers.GetTimeBreakdownHandler)
+			r.Get("/statistics/tags", statisticsHandlers.GetTagBreakdownHandler)
+			r.Get("/statistics/strategies", statisticsHandlers.GetStrategyBreakdownHandler)
+			r.Get("/statistics/checklist", statisticsHandlers.GetAdherenceReportHandler)
+			r.Get("/statistics/symbols", statisticsHandlers.GetSymbolBreakdownHandler)
+			r.Get("/statistics/excursions", statisticsHandlers.GetExcursionReportHandler)
+			r.Get("/statistics/excursions/stops", statisticsHandlers.GetStopSuggestionsHandler)
+			r.Get("/statistics/r-multiples", statisticsHandlers.GetRDistributionHandler)
+			r.Get("/statistics/r-multiples/equity", statisticsHandlers.GetRCurveHandler)
+
+			r.Get("/calendar", statisticsHandlers.GetCalendarHandler)
+
+			r.Post("/import/ninjatrader", importHandlers.ImportNinjatraderTradesHandler)
+			r.Post("/imports/{format}", importHandlers.ImportTradesHandler)
+		})
+	})
 
 	// start the server
 	log.Fatal(http.ListenAndServe(":8080", r))
@@ -0,0 +1,15 @@
+CREATE TABLE executions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    trade_id INTEGER,
+    ticker VARCHAR(10) NOT NULL,
+    side VARCHAR(4) NOT NULL CHECK (side IN ('BUY', 'SELL')),
+    quantity DECIMAL(18, 8) NOT NULL CHECK (quantity > 0),
+    price DECIMAL(18, 8) NOT NULL CHECK (price > 0),
+    executed_at TIMESTAMP NOT NULL,
+    fees DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    FOREIGN KEY (trade_id) REFERENCES trades(id) ON DELETE SET NULL
+);
+
+CREATE INDEX executions_user_id_executed_at_idx ON executions (user_id, executed_at);
+CREATE INDEX executions_trade_id_idx ON executions (trade_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS trades_user_id_fingerprint_key;
+
+ALTER TABLE trades
+  DROP COLUMN IF EXISTS fingerprint,
+  DROP COLUMN IF EXISTS broker_account,
+  DROP COLUMN IF EXISTS source;
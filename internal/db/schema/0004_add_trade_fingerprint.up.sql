@@ -0,0 +1,7 @@
+ALTER TABLE trades
+ADD COLUMN source VARCHAR(50),
+ADD COLUMN broker_account VARCHAR(100),
+ADD COLUMN fingerprint CHAR(64);
+
+-- trades entered by hand have no fingerprint, and NULLs never conflict with each other
+CREATE UNIQUE INDEX trades_user_id_fingerprint_key ON trades (user_id, fingerprint);
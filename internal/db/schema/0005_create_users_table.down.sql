@@ -0,0 +1,5 @@
+ALTER TABLE trades ALTER COLUMN user_id SET DEFAULT 1;
+
+DROP TABLE IF EXISTS sessions;
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,5 @@
+ALTER TABLE executions DROP COLUMN IF EXISTS account_id;
+
+ALTER TABLE trades DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS accounts;
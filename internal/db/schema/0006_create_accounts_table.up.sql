@@ -0,0 +1,36 @@
+CREATE TABLE accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    broker VARCHAR(100),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    starting_balance DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    account_type VARCHAR(4) NOT NULL DEFAULT 'live' CHECK (account_type IN ('live', 'sim', 'prop')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+-- every user with trades or executions gets a default account to hold them
+INSERT INTO accounts (user_id, name)
+SELECT user_id, 'Default' FROM trades
+UNION
+SELECT user_id, 'Default' FROM executions;
+
+-- accounts go with their user. trades from before there were users can still point at a user
+-- that hasn't registered yet, so only new accounts are checked
+ALTER TABLE accounts
+ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE NOT VALID;
+
+ALTER TABLE trades ADD COLUMN account_id INTEGER;
+UPDATE trades t SET account_id = a.id FROM accounts a WHERE a.user_id = t.user_id AND a.name = 'Default';
+ALTER TABLE trades
+ALTER COLUMN account_id SET NOT NULL,
+ADD FOREIGN KEY (account_id) REFERENCES accounts(id);
+
+ALTER TABLE executions ADD COLUMN account_id INTEGER;
+UPDATE executions e SET account_id = a.id FROM accounts a WHERE a.user_id = e.user_id AND a.name = 'Default';
+ALTER TABLE executions
+ALTER COLUMN account_id SET NOT NULL,
+ADD FOREIGN KEY (account_id) REFERENCES accounts(id);
+
+CREATE INDEX trades_account_id_idx ON trades (account_id);
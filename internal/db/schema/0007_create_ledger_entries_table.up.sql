@@ -0,0 +1,15 @@
+CREATE TABLE ledger_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    entry_type VARCHAR(10) NOT NULL CHECK (entry_type IN ('deposit', 'withdrawal', 'fee', 'adjustment')),
+    -- deposits, withdrawals and fees are stored as positive amounts, adjustments carry their own sign
+    amount DECIMAL(14, 2) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX ledger_entries_account_id_occurred_at_idx ON ledger_entries (account_id, occurred_at);
@@ -0,0 +1,9 @@
+ALTER TABLE trades
+ALTER COLUMN entry_price TYPE DECIMAL(10, 2),
+ALTER COLUMN exit_price TYPE DECIMAL(10, 2),
+ALTER COLUMN stop_loss TYPE DECIMAL(10, 2),
+ALTER COLUMN take_profit TYPE DECIMAL(10, 2),
+ALTER COLUMN highest_price TYPE DECIMAL(10, 2),
+ALTER COLUMN lowest_price TYPE DECIMAL(10, 2);
+
+DROP TABLE IF EXISTS instruments;
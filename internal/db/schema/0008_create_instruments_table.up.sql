@@ -0,0 +1,86 @@
+-- contract specifications used to turn price moves into money. rows without a user_id are the
+-- built in catalog, users can add their own rows which take precedence for the same root symbol
+CREATE TABLE instruments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    root_symbol VARCHAR(10) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    asset_class VARCHAR(20) NOT NULL CHECK (asset_class IN ('equity_index', 'interest_rate', 'energy', 'metal', 'agriculture', 'livestock', 'currency', 'crypto')),
+    tick_size DECIMAL(18, 10) NOT NULL CHECK (tick_size > 0),
+    tick_value DECIMAL(14, 4) NOT NULL CHECK (tick_value > 0),
+    point_value DECIMAL(18, 4) NOT NULL CHECK (point_value > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    exchange VARCHAR(10) NOT NULL,
+    trading_hours VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX instruments_catalog_root_symbol_idx ON instruments (root_symbol) WHERE user_id IS NULL;
+CREATE UNIQUE INDEX instruments_user_root_symbol_idx ON instruments (user_id, root_symbol) WHERE user_id IS NOT NULL;
+
+-- futures are quoted in ticks as small as 0.0000005 (6J), two decimals would round them away
+ALTER TABLE trades
+ALTER COLUMN entry_price TYPE DECIMAL(18, 8),
+ALTER COLUMN exit_price TYPE DECIMAL(18, 8),
+ALTER COLUMN stop_loss TYPE DECIMAL(18, 8),
+ALTER COLUMN take_profit TYPE DECIMAL(18, 8),
+ALTER COLUMN highest_price TYPE DECIMAL(18, 8),
+ALTER COLUMN lowest_price TYPE DECIMAL(18, 8);
+
+INSERT INTO instruments (root_symbol, name, asset_class, tick_size, tick_value, point_value, exchange, trading_hours) VALUES
+-- equity index
+('ES', 'E-mini S&P 500', 'equity_index', 0.25, 12.50, 50, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('MES', 'Micro E-mini S&P 500', 'equity_index', 0.25, 1.25, 5, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('NQ', 'E-mini Nasdaq-100', 'equity_index', 0.25, 5.00, 20, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('MNQ', 'Micro E-mini Nasdaq-100', 'equity_index', 0.25, 0.50, 2, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('RTY', 'E-mini Russell 2000', 'equity_index', 0.10, 5.00, 50, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('M2K', 'Micro E-mini Russell 2000', 'equity_index', 0.10, 0.50, 5, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('EMD', 'E-mini S&P MidCap 400', 'equity_index', 0.10, 10.00, 100, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('YM', 'E-mini Dow', 'equity_index', 1, 5.00, 5, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('MYM', 'Micro E-mini Dow', 'equity_index', 1, 0.50, 0.5, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+-- interest rates
+('ZB', 'U.S. Treasury Bond', 'interest_rate', 0.03125, 31.25, 1000, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('UB', 'Ultra U.S. Treasury Bond', 'interest_rate', 0.03125, 31.25, 1000, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('ZN', '10-Year T-Note', 'interest_rate', 0.015625, 15.625, 1000, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('ZF', '5-Year T-Note', 'interest_rate', 0.0078125, 7.8125, 1000, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('ZT', '2-Year T-Note', 'interest_rate', 0.00390625, 7.8125, 2000, 'CBOT', 'Sun-Fri 17:00-16:00 CT'),
+('SR3', 'Three-Month SOFR', 'interest_rate', 0.0025, 6.25, 2500, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+-- energy
+('CL', 'Crude Oil', 'energy', 0.01, 10.00, 1000, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('MCL', 'Micro WTI Crude Oil', 'energy', 0.01, 1.00, 100, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('QM', 'E-mini Crude Oil', 'energy', 0.025, 12.50, 500, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('NG', 'Henry Hub Natural Gas', 'energy', 0.001, 10.00, 10000, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('RB', 'RBOB Gasoline', 'energy', 0.0001, 4.20, 42000, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('HO', 'NY Harbor ULSD', 'energy', 0.0001, 4.20, 42000, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+-- metals
+('GC', 'Gold', 'metal', 0.10, 10.00, 100, 'COMEX', 'Sun-Fri 17:00-16:00 CT'),
+('MGC', 'Micro Gold', 'metal', 0.10, 1.00, 10, 'COMEX', 'Sun-Fri 17:00-16:00 CT'),
+('SI', 'Silver', 'metal', 0.005, 25.00, 5000, 'COMEX', 'Sun-Fri 17:00-16:00 CT'),
+('SIL', 'Micro Silver', 'metal', 0.005, 5.00, 1000, 'COMEX', 'Sun-Fri 17:00-16:00 CT'),
+('HG', 'Copper', 'metal', 0.0005, 12.50, 25000, 'COMEX', 'Sun-Fri 17:00-16:00 CT'),
+('PL', 'Platinum', 'metal', 0.10, 5.00, 50, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+('PA', 'Palladium', 'metal', 0.50, 50.00, 100, 'NYMEX', 'Sun-Fri 17:00-16:00 CT'),
+-- agriculture, quoted in cents
+('ZC', 'Corn', 'agriculture', 0.25, 12.50, 50, 'CBOT', 'Sun-Fri 19:00-07:45, 08:30-13:20 CT'),
+('ZS', 'Soybeans', 'agriculture', 0.25, 12.50, 50, 'CBOT', 'Sun-Fri 19:00-07:45, 08:30-13:20 CT'),
+('ZW', 'Chicago SRW Wheat', 'agriculture', 0.25, 12.50, 50, 'CBOT', 'Sun-Fri 19:00-07:45, 08:30-13:20 CT'),
+('ZM', 'Soybean Meal', 'agriculture', 0.10, 10.00, 100, 'CBOT', 'Sun-Fri 19:00-07:45, 08:30-13:20 CT'),
+('ZL', 'Soybean Oil', 'agriculture', 0.01, 6.00, 600, 'CBOT', 'Sun-Fri 19:00-07:45, 08:30-13:20 CT'),
+-- livestock
+('LE', 'Live Cattle', 'livestock', 0.025, 10.00, 400, 'CME', 'Mon-Fri 08:30-13:05 CT'),
+('GF', 'Feeder Cattle', 'livestock', 0.025, 12.50, 500, 'CME', 'Mon-Fri 08:30-13:05 CT'),
+('HE', 'Lean Hogs', 'livestock', 0.025, 10.00, 400, 'CME', 'Mon-Fri 08:30-13:05 CT'),
+-- currencies
+('6E', 'Euro FX', 'currency', 0.00005, 6.25, 125000, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('M6E', 'Micro EUR/USD', 'currency', 0.0001, 1.25, 12500, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('6B', 'British Pound', 'currency', 0.0001, 6.25, 62500, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('6J', 'Japanese Yen', 'currency', 0.0000005, 6.25, 12500000, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('6A', 'Australian Dollar', 'currency', 0.00005, 5.00, 100000, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('6C', 'Canadian Dollar', 'currency', 0.00005, 5.00, 100000, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('6S', 'Swiss Franc', 'currency', 0.00005, 6.25, 125000, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+-- crypto
+('BTC', 'Bitcoin', 'crypto', 5, 25.00, 5, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('MBT', 'Micro Bitcoin', 'crypto', 5, 0.50, 0.1, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('ETH', 'Ether', 'crypto', 0.50, 25.00, 50, 'CME', 'Sun-Fri 17:00-16:00 CT'),
+('MET', 'Micro Ether', 'crypto', 0.50, 0.05, 0.1, 'CME', 'Sun-Fri 17:00-16:00 CT');
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS trades_user_id_root_symbol_idx;
+
+ALTER TABLE trades DROP COLUMN IF EXISTS root_symbol;
@@ -0,0 +1,13 @@
+-- the ticker keeps the full contract (ESM5, ES 06-25), root_symbol is the continuous root (ES)
+-- so trades can be grouped across rollovers
+ALTER TABLE trades ADD COLUMN root_symbol VARCHAR(10);
+
+UPDATE trades SET root_symbol = COALESCE(
+    substring(upper(ticker) from '^([A-Z0-9]{1,4})\s+\d{2}-(\d{2}|\d{4})$'),
+    substring(upper(ticker) from '^/?([A-Z0-9]{1,4}?)[FGHJKMNQUVXZ](\d{1,2}|\d{4})$'),
+    ltrim(upper(ticker), '/')
+);
+
+ALTER TABLE trades ALTER COLUMN root_symbol SET NOT NULL;
+
+CREATE INDEX trades_user_id_root_symbol_idx ON trades (user_id, root_symbol);
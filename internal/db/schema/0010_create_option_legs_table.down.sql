@@ -0,0 +1,15 @@
+ALTER TABLE trade_metrics
+DROP COLUMN IF EXISTS max_profit,
+DROP COLUMN IF EXISTS max_loss,
+ALTER COLUMN profit_loss_percent TYPE DECIMAL(6, 2),
+ALTER COLUMN risk_reward_ratio TYPE DECIMAL(6, 2);
+
+ALTER TABLE trades
+DROP CONSTRAINT IF EXISTS trades_entry_price_check,
+DROP CONSTRAINT IF EXISTS trades_exit_price_check,
+ADD CONSTRAINT trades_entry_price_check CHECK (entry_price > 0),
+ADD CONSTRAINT trades_exit_price_check CHECK (exit_price > 0);
+
+ALTER TABLE trades DROP COLUMN IF EXISTS strategy;
+
+DROP TABLE IF EXISTS option_legs;
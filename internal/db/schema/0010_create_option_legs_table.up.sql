@@ -0,0 +1,37 @@
+-- an options trade is a journal trade with one or more legs. multi-leg strategies (verticals,
+-- iron condors, straddles) are one trade with a leg per contract
+CREATE TABLE option_legs (
+    id SERIAL PRIMARY KEY,
+    trade_id INTEGER NOT NULL,
+    underlying VARCHAR(10) NOT NULL,
+    option_type VARCHAR(4) NOT NULL CHECK (option_type IN ('CALL', 'PUT')),
+    strike DECIMAL(12, 4) NOT NULL CHECK (strike > 0),
+    expiry DATE NOT NULL,
+    side VARCHAR(4) NOT NULL CHECK (side IN ('BUY', 'SELL')),
+    quantity DECIMAL(10, 2) NOT NULL CHECK (quantity > 0),
+    entry_price DECIMAL(12, 4) NOT NULL CHECK (entry_price >= 0),
+    exit_price DECIMAL(12, 4) NOT NULL CHECK (exit_price >= 0),
+    multiplier DECIMAL(10, 2) NOT NULL DEFAULT 100 CHECK (multiplier > 0),
+    commissions DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    profit_loss DECIMAL(14, 2),
+    FOREIGN KEY (trade_id) REFERENCES trades(id) ON DELETE CASCADE
+);
+
+CREATE INDEX option_legs_trade_id_idx ON option_legs (trade_id);
+
+ALTER TABLE trades ADD COLUMN strategy VARCHAR(20);
+
+-- the net premium of a spread can be zero, and options that expire worthless exit at zero
+ALTER TABLE trades
+DROP CONSTRAINT IF EXISTS trades_entry_price_check,
+DROP CONSTRAINT IF EXISTS trades_exit_price_check,
+ADD CONSTRAINT trades_entry_price_check CHECK (entry_price >= 0),
+ADD CONSTRAINT trades_exit_price_check CHECK (exit_price >= 0);
+
+-- defined risk strategies know their best and worst case at expiry, NULL means unlimited or unknown.
+-- a cheap option or a credit spread with a small max loss easily returns more than 9999.99%
+ALTER TABLE trade_metrics
+ADD COLUMN max_profit DECIMAL(14, 2),
+ADD COLUMN max_loss DECIMAL(14, 2),
+ALTER COLUMN profit_loss_percent TYPE DECIMAL(14, 2),
+ALTER COLUMN risk_reward_ratio TYPE DECIMAL(14, 2);
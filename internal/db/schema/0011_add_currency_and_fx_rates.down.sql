@@ -0,0 +1,5 @@
+DROP FUNCTION IF EXISTS fx_rate(INTEGER, CHAR(3), CHAR(3), TIMESTAMP);
+
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE trades DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,40 @@
+-- P&L is stored in the currency the trade was made in, statistics convert it to the
+-- account's currency with the fx rate at exit time
+ALTER TABLE trades ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE trades t SET currency = a.currency FROM accounts a WHERE a.id = t.account_id;
+-- futures P&L comes from the tick value, so it's in the instrument's currency. the user's own
+-- instrument goes last so it wins over the catalog
+UPDATE trades t SET currency = i.currency FROM instruments i
+WHERE i.root_symbol = t.root_symbol AND i.user_id IS NULL;
+UPDATE trades t SET currency = i.currency FROM instruments i
+WHERE i.root_symbol = t.root_symbol AND i.user_id = t.user_id;
+ALTER TABLE trades ALTER COLUMN currency DROP DEFAULT;
+
+-- rate is how much of the quote currency one unit of the base currency buys, as of a point in time
+CREATE TABLE fx_rates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+    as_of TIMESTAMP NOT NULL,
+    UNIQUE(user_id, base_currency, quote_currency, as_of),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- the latest rate on or before p_at to convert p_from into p_to, using the inverse pair if that's
+-- what's been loaded. NULL if there's no rate yet
+CREATE FUNCTION fx_rate(p_user_id INTEGER, p_from CHAR(3), p_to CHAR(3), p_at TIMESTAMP)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN p_from = p_to THEN 1 ELSE (
+        SELECT r.rate FROM (
+            SELECT rate, as_of FROM fx_rates
+            WHERE user_id = p_user_id AND base_currency = p_from AND quote_currency = p_to AND as_of <= p_at
+            UNION ALL
+            SELECT 1 / rate, as_of FROM fx_rates
+            WHERE user_id = p_user_id AND base_currency = p_to AND quote_currency = p_from AND as_of <= p_at
+        ) r
+        ORDER BY r.as_of DESC
+        LIMIT 1
+    ) END
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS mark_prices;
+
+DROP INDEX IF EXISTS trades_open_idx;
+
+-- open positions can't be kept without an exit
+DELETE FROM trades WHERE status = 'OPEN';
+
+ALTER TABLE trades
+DROP CONSTRAINT IF EXISTS trades_exit_status_check,
+DROP COLUMN IF EXISTS status,
+ALTER COLUMN exit_time SET DEFAULT CURRENT_TIMESTAMP,
+ALTER COLUMN exit_time SET NOT NULL;
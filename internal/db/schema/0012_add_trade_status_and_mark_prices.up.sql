@@ -0,0 +1,23 @@
+-- open positions have no exit yet. everything journaled so far was closed
+ALTER TABLE trades
+ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'CLOSED' CHECK (status IN ('OPEN', 'CLOSED')),
+ALTER COLUMN exit_time DROP NOT NULL,
+ALTER COLUMN exit_time DROP DEFAULT,
+ADD CONSTRAINT trades_exit_status_check CHECK (
+    (status = 'OPEN' AND exit_price IS NULL AND exit_time IS NULL)
+    OR (status = 'CLOSED' AND exit_price IS NOT NULL AND exit_time IS NOT NULL)
+);
+
+CREATE INDEX trades_open_idx ON trades (user_id) WHERE status = 'OPEN';
+
+-- the last known price of a symbol, used to value open positions when the request doesn't
+-- bring its own. symbol is either a ticker like ESM5 or a root like ES
+CREATE TABLE mark_prices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    symbol VARCHAR(30) NOT NULL,
+    price DECIMAL(18, 8) NOT NULL CHECK (price >= 0),
+    as_of TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, symbol),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
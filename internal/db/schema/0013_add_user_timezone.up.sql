@@ -0,0 +1,2 @@
+-- timestamps are stored in UTC, reports that group by day or hour convert them to this first
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
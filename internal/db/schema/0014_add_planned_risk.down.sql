@@ -0,0 +1,7 @@
+-- r_multiple keeps its wider type, the R-multiples in it may not fit the old one
+ALTER TABLE trade_metrics DROP COLUMN IF EXISTS risk_amount;
+
+ALTER TABLE trades
+DROP CONSTRAINT IF EXISTS trades_planned_risk_check,
+DROP COLUMN IF EXISTS planned_risk_ticks,
+DROP COLUMN IF EXISTS planned_risk;
@@ -0,0 +1,43 @@
+-- the risk planned before the entry, either in money or in ticks. the R-multiple is worked out
+-- from it when it's there
+ALTER TABLE trades
+ADD COLUMN planned_risk DECIMAL(12, 2) CHECK (planned_risk > 0),
+ADD COLUMN planned_risk_ticks DECIMAL(10, 2) CHECK (planned_risk_ticks > 0),
+ADD CONSTRAINT trades_planned_risk_check CHECK (planned_risk IS NULL OR planned_risk_ticks IS NULL);
+
+-- the money a trade put at risk, R-multiples are the P&L over it. NULL when the risk isn't known.
+-- a small planned risk makes for a big R, so it gets more room
+ALTER TABLE trade_metrics
+ADD COLUMN risk_amount DECIMAL(12, 2),
+ALTER COLUMN r_multiple TYPE DECIMAL(10, 2);
+
+-- defined risk options strategies risked their max loss
+UPDATE trade_metrics SET risk_amount = max_loss WHERE max_loss > 0;
+
+-- everything else with a stop risked the distance to it, valued in whole ticks for futures, plus
+-- the commissions. the R-multiples are redone in money from it
+UPDATE trade_metrics tm
+SET risk_amount = r.amount, r_multiple = ROUND(tm.profit_loss / r.amount, 2)
+FROM (
+    SELECT
+        t.id,
+        CASE
+            WHEN i.tick_size > 0 THEN ROUND(s.distance / i.tick_size) * i.tick_value * t.quantity
+            ELSE s.distance * t.quantity
+        END + COALESCE(t.commissions, 0) AS amount
+    FROM trades t
+    CROSS JOIN LATERAL (
+        SELECT CASE WHEN UPPER(t.direction) = 'SHORT' THEN t.stop_loss - t.entry_price
+            ELSE t.entry_price - t.stop_loss END AS distance
+    ) s
+    LEFT JOIN LATERAL (
+        SELECT tick_size, tick_value
+        FROM instruments
+        WHERE root_symbol = t.root_symbol AND (user_id IS NULL OR user_id = t.user_id)
+        ORDER BY user_id NULLS LAST
+        LIMIT 1
+    ) i ON true
+    WHERE t.stop_loss > 0 AND s.distance > 0
+        AND NOT EXISTS (SELECT 1 FROM option_legs ol WHERE ol.trade_id = t.id)
+) r
+WHERE tm.trade_id = r.id AND tm.risk_amount IS NULL AND r.amount > 0;
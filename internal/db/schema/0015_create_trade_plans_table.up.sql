@@ -0,0 +1,19 @@
+-- the plan written before a trade. it's linked to the trade once it's taken, a trade has at
+-- most one plan
+CREATE TABLE trade_plans (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    trade_id INTEGER UNIQUE,
+    ticker VARCHAR(30) NOT NULL,
+    direction VARCHAR(5) NOT NULL CHECK (direction IN ('LONG', 'SHORT')),
+    thesis TEXT,
+    entry_price DECIMAL(18, 8) NOT NULL CHECK (entry_price >= 0),
+    stop_loss DECIMAL(18, 8),
+    target_price DECIMAL(18, 8),
+    quantity DECIMAL(10, 2) NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (trade_id) REFERENCES trades(id) ON DELETE SET NULL
+);
+
+CREATE INDEX trade_plans_user_id_idx ON trade_plans (user_id);
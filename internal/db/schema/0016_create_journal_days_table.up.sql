@@ -0,0 +1,18 @@
+-- one journal entry per trading day, with the plan before the open and the review after the
+-- close. the day's trades aren't stored, they're found by their exit time
+CREATE TABLE journal_days (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    journal_date DATE NOT NULL,
+    pre_market_plan TEXT,
+    market_bias VARCHAR(10) CHECK (market_bias IN ('bullish', 'bearish', 'neutral')),
+    -- 1 for rattled up to 5 for calm and focused
+    emotional_state SMALLINT CHECK (emotional_state BETWEEN 1 AND 5),
+    post_session_review TEXT,
+    lessons TEXT,
+    grade CHAR(1) CHECK (grade IN ('A', 'B', 'C', 'D', 'F')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, journal_date),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE trades DROP COLUMN IF EXISTS strategy_id;
+
+DROP TABLE IF EXISTS strategies;
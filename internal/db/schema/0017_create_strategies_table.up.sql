@@ -0,0 +1,22 @@
+-- the playbook: the setups a user trades, with their rules. not to be confused with the
+-- strategy column of trades, which is the structure of an options trade
+CREATE TABLE strategies (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    entry_rules TEXT,
+    exit_rules TEXT,
+    risk_rules TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- a trade is taken on at most one strategy, deleting the strategy leaves its trades without one
+ALTER TABLE trades
+ADD COLUMN strategy_id INTEGER,
+ADD FOREIGN KEY (strategy_id) REFERENCES strategies(id) ON DELETE SET NULL;
+
+CREATE INDEX trades_strategy_id_idx ON trades (strategy_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS trade_checklist;
+
+DROP TABLE IF EXISTS checklist_items;
@@ -0,0 +1,23 @@
+-- the checklist of a strategy, the rules every trade taken on it should follow
+CREATE TABLE checklist_items (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    strategy_id INTEGER NOT NULL,
+    rule VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (strategy_id) REFERENCES strategies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX checklist_items_strategy_id_idx ON checklist_items (strategy_id);
+
+-- a trade's answers to its strategy's checklist, like trade_tags with whether the rule was followed
+CREATE TABLE trade_checklist (
+    trade_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    followed BOOLEAN NOT NULL,
+    PRIMARY KEY (trade_id, item_id),
+    FOREIGN KEY (trade_id) REFERENCES trades(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES checklist_items(id) ON DELETE CASCADE
+);
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AccountHandlers struct {
+	db *sql.DB
+}
+
+func NewAccountHandlers(db *sql.DB) *AccountHandlers {
+	return &AccountHandlers{db: db}
+}
+
+func (h *AccountHandlers) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var account models.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	account.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateAccount(&account); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := models.CreateAccount(h.db, &account)
+	if errors.Is(err, models.ErrAccountNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create account: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(account); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *AccountHandlers) ListAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := models.ListAccounts(h.db, userIDFromContext(r.Context()))
+	if err != nil {
+		http.Error(w, "Failed to retrieve accounts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(accounts); err != nil {
+		http.Error(w, "Failed to encode accounts", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandlers) GetAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := models.GetAccount(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve account: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(account); err != nil {
+		http.Error(w, "Failed to encode account", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandlers) UpdateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var account models.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	account.ID = id
+	account.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateAccount(&account); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.UpdateAccount(h.db, &account)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrAccountNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update account: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(account); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandlers) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteAccount(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrAccountHasTrades) {
+		http.Error(w, "Account still has trades or executions, move or delete them first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete account: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandlers) ListLedgerEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := models.ListLedgerEntries(h.db, userIDFromContext(r.Context()), accountID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve ledger: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Failed to encode ledger", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to record a deposit, withdrawal, fee or adjustment on an account
+func (h *AccountHandlers) AddLedgerEntryHandler(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var entry models.LedgerEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry.UserID = userIDFromContext(r.Context())
+	entry.AccountID = accountID
+	if err := models.ValidateLedgerEntry(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.AddLedgerEntry(h.db, &entry)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add ledger entry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *AccountHandlers) DeleteLedgerEntryHandler(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	entryID, err := strconv.Atoi(chi.URLParam(r, "entry_id"))
+	if err != nil {
+		http.Error(w, "Invalid ledger entry ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteLedgerEntry(h.db, userIDFromContext(r.Context()), accountID, entryID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Ledger entry not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete ledger entry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trades and fills that don't say which account they're for go into the user's default account
+func defaultAccountID(db *sql.DB, userID int) (int, error) {
+	return models.GetOrCreateAccountByName(db, userID, models.DefaultAccountName, nil)
+}
+
+// parse the optional account_id query parameter
+func accountIDParam(r *http.Request) (*int, error) {
+	raw := r.URL.Query().Get("account_id")
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"trading-journal/internal/models"
+)
+
+const (
+	sessionCookieName = "session_token"
+	sessionTTL        = 30 * 24 * time.Hour
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt refuses to hash anything longer
+)
+
+type contextKey string
+
+const userIDContextKey contextKey = "user_id"
+
+type AuthHandlers struct {
+	db *sql.DB
+}
+
+func NewAuthHandlers(db *sql.DB) *AuthHandlers {
+	return &AuthHandlers{db: db}
+}
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type sessionResponse struct {
+	User      models.User `json:"user"`
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+func (h *AuthHandlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(creds.Email, "@") {
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+	if len(creds.Password) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+	if len(creds.Password) > maxPasswordLength {
+		http.Error(w, "Password must be at most 72 bytes", http.StatusBadRequest)
+		return
+	}
+
+	user, err := models.CreateUser(h.db, creds.Email, creds.Password)
+	if errors.Is(err, models.ErrEmailTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error registering user: %v", err)
+		http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		return
+	}
+
+	// log the new user straight in
+	h.startSession(w, user, http.StatusCreated)
+}
+
+func (h *AuthHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := models.AuthenticateUser(h.db, creds.Email, creds.Password)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Error logging in: %v", err)
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	h.startSession(w, user, http.StatusOK)
+}
+
+func (h *AuthHandlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if token := sessionToken(r); token != "" {
+		if err := models.DeleteSession(h.db, token); err != nil {
+			log.Printf("Error deleting session: %v", err)
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// expire the cookie in the browser too
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler that returns the logged in user
+func (h *AuthHandlers) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := models.GetUserByID(h.db, userIDFromContext(r.Context()))
+	if err != nil {
+		http.Error(w, "Failed to retrieve user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, "Failed to encode user", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to change the logged in user's settings, for now only the timezone
+func (h *AuthHandlers) UpdateMeHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	err := models.UpdateUserTimezone(h.db, userID, body.Timezone)
+	if errors.Is(err, models.ErrInvalidTimezone) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.MeHandler(w, r)
+}
+
+// RequireAuth is middleware that rejects requests without a valid session and puts the
+// session's user ID in the request context for the handlers further down
+func (h *AuthHandlers) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := sessionToken(r)
+		if token == "" {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := models.GetSessionUserID(h.db, token)
+		if errors.Is(err, models.ErrSessionNotFound) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("Error checking session: %v", err)
+			http.Error(w, "Failed to check session", http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *AuthHandlers) startSession(w http.ResponseWriter, user models.User, status int) {
+	token, expiresAt, err := models.CreateSession(h.db, user.ID, sessionTTL)
+	if err != nil {
+		log.Printf("Error creating session for user %d: %v", user.ID, err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	// the browser uses the cookie, other clients can send the token as a bearer token
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(sessionResponse{User: user, Token: token, ExpiresAt: expiresAt}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// get the session token from the Authorization header, or the cookie if there isn't one
+func sessionToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// userIDFromContext returns the user RequireAuth put in the context
+func userIDFromContext(ctx context.Context) int {
+	userID, _ := ctx.Value(userIDContextKey).(int)
+	return userID
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ChecklistHandlers struct {
+	db *sql.DB
+}
+
+func NewChecklistHandlers(db *sql.DB) *ChecklistHandlers {
+	return &ChecklistHandlers{db: db}
+}
+
+// handler to add a rule to a strategy's checklist
+func (h *ChecklistHandlers) CreateChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	strategyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+
+	var item models.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.UserID = userIDFromContext(r.Context())
+	item.StrategyID = strategyID
+	if err := models.ValidateChecklistItem(&item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.CreateChecklistItem(h.db, &item)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Strategy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create checklist item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *ChecklistHandlers) ListChecklistItemsHandler(w http.ResponseWriter, r *http.Request) {
+	strategyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+
+	items, err := models.ListChecklistItems(h.db, userIDFromContext(r.Context()), strategyID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Strategy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve checklist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		http.Error(w, "Failed to encode checklist", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ChecklistHandlers) UpdateChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	strategyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid checklist item ID", http.StatusBadRequest)
+		return
+	}
+
+	var item models.ChecklistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	item.ID = itemID
+	item.StrategyID = strategyID
+	item.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateChecklistItem(&item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.UpdateChecklistItem(h.db, &item)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Checklist item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update checklist item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ChecklistHandlers) DeleteChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	strategyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid checklist item ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteChecklistItem(h.db, userIDFromContext(r.Context()), strategyID, itemID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Checklist item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete checklist item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler for the checklist of a trade's strategy with the trade's answers
+func (h *ChecklistHandlers) GetTradeChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	tradeID, err := strconv.Atoi(chi.URLParam(r, "trade_id"))
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	answers, err := models.GetTradeChecklist(h.db, userIDFromContext(r.Context()), tradeID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve trade checklist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(answers); err != nil {
+		http.Error(w, "Failed to encode trade checklist", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to answer a rule for a trade, the body is {"followed": true} or false
+func (h *ChecklistHandlers) AnswerChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	tradeID, err := strconv.Atoi(chi.URLParam(r, "trade_id"))
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid checklist item ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Followed *bool `json:"followed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Followed == nil {
+		http.Error(w, "followed is required", http.StatusBadRequest)
+		return
+	}
+
+	err = models.AnswerChecklistItem(h.db, userIDFromContext(r.Context()), tradeID, itemID, *body.Followed)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade or checklist item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to answer checklist item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChecklistHandlers) RemoveChecklistAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	tradeID, err := strconv.Atoi(chi.URLParam(r, "trade_id"))
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "item_id"))
+	if err != nil {
+		http.Error(w, "Invalid checklist item ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.RemoveChecklistAnswer(h.db, userIDFromContext(r.Context()), tradeID, itemID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Checklist answer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove checklist answer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"trading-journal/internal/models"
+	"trading-journal/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ExecutionHandlers struct {
+	db *sql.DB
+}
+
+func NewExecutionHandlers(db *sql.DB) *ExecutionHandlers {
+	return &ExecutionHandlers{db: db}
+}
+
+// handler to record one or more fills. the body is a json array of executions
+func (h *ExecutionHandlers) AddExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	var executions []models.Execution
+	if err := json.NewDecoder(r.Body).Decode(&executions); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	// fills without an account go into the default one
+	defaultAccount := 0
+	for i := range executions {
+		executions[i].UserID = userID
+		if executions[i].AccountID == 0 {
+			if defaultAccount == 0 {
+				id, err := defaultAccountID(h.db, userID)
+				if err != nil {
+					http.Error(w, "Failed to add executions: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				defaultAccount = id
+			}
+			executions[i].AccountID = defaultAccount
+		}
+		executions[i].TradeID = nil
+		executions[i].Side = strings.ToUpper(executions[i].Side)
+		if executions[i].Side != "BUY" && executions[i].Side != "SELL" {
+			http.Error(w, "side must be BUY or SELL", http.StatusBadRequest)
+			return
+		}
+		if executions[i].Quantity <= 0 || executions[i].Price <= 0 {
+			http.Error(w, "quantity and price must be greater than 0", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		http.Error(w, "Failed to add executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for i := range executions {
+		id, err := models.AddExecution(tx, executions[i])
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to add executions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		executions[i].ID = id
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to add executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(executions); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *ExecutionHandlers) ListExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	// ?unmatched=true only returns fills that aren't part of a trade yet
+	var executions []models.Execution
+	var err error
+	if r.URL.Query().Get("unmatched") == "true" {
+		executions, err = models.GetUnmatchedExecutions(h.db, userID)
+	} else {
+		executions, err = models.ListExecutions(h.db, userID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(executions); err != nil {
+		http.Error(w, "Failed to encode executions", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ExecutionHandlers) DeleteExecutionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+
+	err = models.DeleteExecution(h.db, id, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Execution not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete execution: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler to group the unmatched fills into trades. ?method=fifo (default) or ?method=average
+func (h *ExecutionHandlers) MatchExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	method, err := services.ParseMatchingMethod(r.URL.Query().Get("method"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+
+	report, err := services.MatchExecutionsToTrades(h.db, userID, method)
+	if err != nil {
+		log.Printf("Error matching executions for user %d: %v", userID, err)
+		http.Error(w, "Failed to match executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *ExecutionHandlers) GetTradeExecutionsHandler(w http.ResponseWriter, r *http.Request) {
+	tradeID, err := strconv.Atoi(chi.URLParam(r, "trade_id"))
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	executions, err := models.GetExecutionsByTradeID(h.db, userIDFromContext(r.Context()), tradeID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve executions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(executions); err != nil {
+		http.Error(w, "Failed to encode executions", http.StatusInternalServerError)
+		return
+	}
+}
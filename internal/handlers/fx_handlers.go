@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+	"trading-journal/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FXHandlers struct {
+	db *sql.DB
+}
+
+func NewFXHandlers(db *sql.DB) *FXHandlers {
+	return &FXHandlers{db: db}
+}
+
+// handler that lists the user's fx rates, ?base= and ?quote= narrow them down to one pair
+func (h *FXHandlers) ListFXRatesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	rates, err := models.ListFXRates(h.db, userIDFromContext(r.Context()), query.Get("base"), query.Get("quote"))
+	if err != nil {
+		http.Error(w, "Failed to retrieve fx rates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rates); err != nil {
+		http.Error(w, "Failed to encode fx rates", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to add a single rate, a rate for the same pair and time is replaced
+func (h *FXHandlers) SaveFXRateHandler(w http.ResponseWriter, r *http.Request) {
+	var rate models.FXRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	rate.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateFXRate(&rate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveFXRate(h.db, &rate); err != nil {
+		http.Error(w, "Failed to save fx rate: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rate); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *FXHandlers) DeleteFXRateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid fx rate ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteFXRate(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "FX rate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete fx rate: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler for POST /api/fx-rates/import, the csv is uploaded as multipart form data under "file"
+func (h *FXHandlers) ImportFXRatesHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max
+		http.Error(w, "Failed to parse form data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file in form field \"file\"", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	userID := userIDFromContext(r.Context())
+	report, err := services.ImportFXRates(h.db, userID, file)
+	if err != nil {
+		log.Printf("Error importing fx rates for user %d: %v", userID, err)
+		http.Error(w, "Failed to import fx rates: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding import response: %v", err)
+	}
+}
@@ -1,39 +1,103 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
+	"trading-journal/internal/models"
 	"trading-journal/internal/services"
+
+	"github.com/go-chi/chi/v5"
 )
 
-func ImportNinjatraderTradesHandler(w http.ResponseWriter, r *http.Request) {
+type ImportHandlers struct {
+	db *sql.DB
+}
+
+func NewImportHandlers(db *sql.DB) *ImportHandlers {
+	return &ImportHandlers{db: db}
+}
+
+// handler for POST /api/imports/{format}, which hands the file to the importer registered for that format
+func (h *ImportHandlers) ImportTradesHandler(w http.ResponseWriter, r *http.Request) {
+	h.importTrades(w, r, chi.URLParam(r, "format"))
+}
+
+func (h *ImportHandlers) ImportNinjatraderTradesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	importer := services.NewNinjaTraderImporterService()
+	h.importTrades(w, r, "ninjatrader")
+}
+
+func (h *ImportHandlers) importTrades(w http.ResponseWriter, r *http.Request, format string) {
+	importer, ok := services.GetImporter(format)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown import format %q, supported formats: %s", format, strings.Join(services.ImportFormats(), ", ")), http.StatusNotFound)
+		return
+	}
 
-	importedTrades, err := importer.ImportTrades()
+	// the statement is uploaded as multipart form data under "file", with optional json options under "options"
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max
+		http.Error(w, "Failed to parse form data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
 	if err != nil {
-		log.Printf("Error importing NinjaTrader trades: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to import trades: %s", err.Error()), http.StatusInternalServerError)
+		http.Error(w, "Missing file in form field \"file\"", http.StatusBadRequest)
 		return
 	}
+	defer file.Close()
 
-	// TODO: make it save these imported trades to the database after I fix these bugs
+	var options services.ImportOptions
+	if raw := r.FormValue("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			http.Error(w, "Invalid import options: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	// timestamps without an offset are in the user's own timezone unless the options say otherwise
+	if options.Timezone == "" {
+		user, err := models.GetUserByID(h.db, userID)
+		if err != nil {
+			http.Error(w, "Failed to get user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		options.Timezone = user.Timezone
+	}
+
+	importedTrades, err := importer.Import(file, options)
+	if err != nil {
+		log.Printf("Error importing %s trades: %v", format, err)
+		http.Error(w, fmt.Sprintf("Failed to import trades: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
 
-	response := map[string]interface{}{
-		"message":       "Trade import process completed.",
-		"importedCount": len(importedTrades),
+	report, err := services.SaveImportedTrades(h.db, userID, strings.ToLower(format), importedTrades, options)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving imported %s trades: %v", format, err)
+		http.Error(w, fmt.Sprintf("Failed to save imported trades: %s", err.Error()), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(report); err != nil {
 		log.Printf("Error encoding import response: %v", err)
 	}
-	log.Printf("Successfully handled NinjaTrader import request. Found %d potential trades.", len(importedTrades))
+	log.Printf("Successfully handled %s import request. Imported %d trades, skipped %d, updated %d, %d rows failed.",
+		format, report.ImportedCount, report.SkippedCount, report.UpdatedCount, report.FailedCount)
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type InstrumentHandlers struct {
+	db *sql.DB
+}
+
+func NewInstrumentHandlers(db *sql.DB) *InstrumentHandlers {
+	return &InstrumentHandlers{db: db}
+}
+
+// handler that lists the contract catalog, with the user's own instruments in place of the catalog ones
+func (h *InstrumentHandlers) ListInstrumentsHandler(w http.ResponseWriter, r *http.Request) {
+	instruments, err := models.ListInstruments(h.db, userIDFromContext(r.Context()))
+	if err != nil {
+		http.Error(w, "Failed to retrieve instruments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(instruments); err != nil {
+		http.Error(w, "Failed to encode instruments", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *InstrumentHandlers) GetInstrumentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid instrument ID", http.StatusBadRequest)
+		return
+	}
+
+	instrument, err := models.GetInstrument(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Instrument not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve instrument: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		http.Error(w, "Failed to encode instrument", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to add a custom instrument, or override a catalog one by using the same root symbol
+func (h *InstrumentHandlers) CreateInstrumentHandler(w http.ResponseWriter, r *http.Request) {
+	var instrument models.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&instrument); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateInstrument(&instrument); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := models.CreateInstrument(h.db, userIDFromContext(r.Context()), &instrument)
+	if errors.Is(err, models.ErrInstrumentExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create instrument: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *InstrumentHandlers) UpdateInstrumentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid instrument ID", http.StatusBadRequest)
+		return
+	}
+
+	var instrument models.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&instrument); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	instrument.ID = id
+	if err := models.ValidateInstrument(&instrument); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// catalog instruments don't belong to the user, so they come back as not found here
+	err = models.UpdateInstrument(h.db, userIDFromContext(r.Context()), &instrument)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Instrument not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrInstrumentExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update instrument: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *InstrumentHandlers) DeleteInstrumentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid instrument ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteInstrument(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Instrument not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete instrument: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
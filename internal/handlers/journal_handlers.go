@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type JournalHandlers struct {
+	db *sql.DB
+}
+
+func NewJournalHandlers(db *sql.DB) *JournalHandlers {
+	return &JournalHandlers{db: db}
+}
+
+// handler for the journal days, ?from= and ?to= (YYYY-MM-DD) narrow down the dates
+func (h *JournalHandlers) ListJournalDaysHandler(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			http.Error(w, "Invalid from or to parameter, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	days, err := models.ListJournalDays(h.db, userIDFromContext(r.Context()), from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve journal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(days); err != nil {
+		http.Error(w, "Failed to encode journal", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for a journal day with the trades closed that day, ?currency= works like it does for
+// the statistics
+func (h *JournalHandlers) GetJournalDayHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := journalDateParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+
+	detail, err := models.GetJournalDayDetail(h.db, userID, date, r.URL.Query().Get("currency"))
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Journal day not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting journal day %s for user %d: %+v", date, userID, err)
+		http.Error(w, "Failed to retrieve journal day: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		http.Error(w, "Failed to encode journal day", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to write the journal of a day, it replaces whatever the day had
+func (h *JournalHandlers) SaveJournalDayHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := journalDateParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var day models.JournalDay
+	if err := json.NewDecoder(r.Body).Decode(&day); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	day.UserID = userIDFromContext(r.Context())
+	day.Date = date
+	if err := models.ValidateJournalDay(&day); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveJournalDay(h.db, &day); err != nil {
+		http.Error(w, "Failed to save journal day: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(day); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *JournalHandlers) DeleteJournalDayHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := journalDateParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteJournalDay(h.db, userIDFromContext(r.Context()), date)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Journal day not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete journal day: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler for how the ratings in the journal line up with the P&L of the same days. it takes
+// the same filters as the statistics
+func (h *JournalHandlers) GetJournalCorrelationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	correlation, err := models.GetJournalCorrelation(h.db, userID, filter)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting journal correlation for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve journal correlation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(correlation); err != nil {
+		http.Error(w, "Failed to encode journal correlation", http.StatusInternalServerError)
+		return
+	}
+}
+
+// journalDateParam reads the {date} of the url as a YYYY-MM-DD date
+func journalDateParam(r *http.Request) (string, error) {
+	date, err := time.Parse("2006-01-02", chi.URLParam(r, "date"))
+	if err != nil {
+		return "", models.ErrInvalidJournalDate
+	}
+	return date.Format("2006-01-02"), nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PositionHandlers struct {
+	db *sql.DB
+}
+
+func NewPositionHandlers(db *sql.DB) *PositionHandlers {
+	return &PositionHandlers{db: db}
+}
+
+// handler for the open positions and their unrealized P&L. mark prices can be sent as
+// ?mark=ESM5:5012.25&mark=AAPL:190.5, anything without one uses the stored mark price.
+// ?account_id=, ?root_symbol= and ?currency= work like they do for the statistics
+func (h *PositionHandlers) ListOpenPositionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	accountID, err := accountIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid account_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	marks := make(map[string]float64)
+	for _, param := range r.URL.Query()["mark"] {
+		for _, mark := range strings.Split(param, ",") {
+			symbol, price, ok := strings.Cut(mark, ":")
+			value, err := strconv.ParseFloat(strings.TrimSpace(price), 64)
+			if !ok || err != nil || value < 0 {
+				http.Error(w, "Invalid mark parameter, use SYMBOL:PRICE", http.StatusBadRequest)
+				return
+			}
+			marks[strings.ToUpper(strings.TrimSpace(symbol))] = value
+		}
+	}
+
+	filter := models.StatsFilter{
+		TradeFilter: models.TradeFilter{AccountID: accountID, RootSymbol: r.URL.Query().Get("root_symbol")},
+		Currency:    r.URL.Query().Get("currency"),
+	}
+	positions, err := models.GetOpenPositions(h.db, userID, filter, marks)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting open positions for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve open positions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(positions); err != nil {
+		http.Error(w, "Failed to encode open positions", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *PositionHandlers) ListMarkPricesHandler(w http.ResponseWriter, r *http.Request) {
+	marks, err := models.ListMarkPrices(h.db, userIDFromContext(r.Context()))
+	if err != nil {
+		http.Error(w, "Failed to retrieve mark prices: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(marks); err != nil {
+		http.Error(w, "Failed to encode mark prices", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to set the mark price of a symbol, as_of defaults to now
+func (h *PositionHandlers) SaveMarkPriceHandler(w http.ResponseWriter, r *http.Request) {
+	var mark models.MarkPrice
+	if err := json.NewDecoder(r.Body).Decode(&mark); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	mark.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateMarkPrice(&mark); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SaveMarkPrice(h.db, &mark); err != nil {
+		http.Error(w, "Failed to save mark price: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mark); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *PositionHandlers) DeleteMarkPriceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid mark price ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteMarkPrice(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Mark price not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete mark price: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
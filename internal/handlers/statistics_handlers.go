@@ -3,8 +3,10 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"trading-journal/internal/models"
 )
 
@@ -17,15 +19,28 @@ func NewStatisticsHandlers(db *sql.DB) *StatisticsHandlers {
 }
 
 func (h *StatisticsHandlers) GetStatisticsHandler(w http.ResponseWriter, r *http.Request) {
-	// auth will be implemented later, for now i'll use ID 1
-	userID := 1
+	userID := userIDFromContext(r.Context())
 
 	// enable CORS
 	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
-	stats, err := models.GetBasicStats(h.db, userID)
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := models.GetBasicStats(h.db, userID, filter)
+	if errors.Is(err, models.ErrNoTrades) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting statistics for user %d: %+v", userID, err)
 		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
@@ -38,4 +53,402 @@ func (h *StatisticsHandlers) GetStatisticsHandler(w http.ResponseWriter, r *http
 		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
 		return
 	}
-} 
\ No newline at end of file
+}
+
+// handler that returns every account's statistics side by side
+func (h *StatisticsHandlers) GetAccountComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	comparison, err := models.GetAccountComparison(h.db, userID)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error comparing accounts for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(comparison); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the equity curve of all accounts, or one with ?account_id=, in ?currency= if given
+func (h *StatisticsHandlers) GetEquityCurveHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	accountID, err := accountIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid account_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	filter := models.StatsFilter{
+		TradeFilter: models.TradeFilter{AccountID: accountID},
+		Currency:    r.URL.Query().Get("currency"),
+	}
+	curve, err := models.GetEquityCurve(h.db, userID, filter)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting equity curve for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve equity curve: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(curve); err != nil {
+		log.Printf("Error encoding equity curve: %+v", err)
+		http.Error(w, "Failed to encode equity curve", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler that groups the filtered trades by ?bucket=day, week, month, hour, weekday or session,
+// in the user's timezone
+func (h *StatisticsHandlers) GetTimeBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := models.GetTimeBreakdown(h.db, userID, filter, r.URL.Query().Get("bucket"))
+	if errors.Is(err, models.ErrUnknownTimeBucket) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting time breakdown for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// statsFilterFromQuery reads the same filters as the trade list (?account_id=, ?root_symbol=,
+// ?tag_id=, ?start_date= and so on), plus ?currency= to report in another currency than the
+// account's
+func statsFilterFromQuery(r *http.Request) (models.StatsFilter, error) {
+	tradeFilter, err := tradeFilterFromQuery(r)
+	if err != nil {
+		return models.StatsFilter{}, err
+	}
+	return models.StatsFilter{TradeFilter: tradeFilter, Currency: r.URL.Query().Get("currency")}, nil
+}
+
+// handler for the performance by tag and by tag category. ?tag_id=1,2 narrows it down to the
+// trades with both tags and ?exclude_tag_id=3 leaves out the ones tagged 3
+func (h *StatisticsHandlers) GetTagBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := models.GetTagBreakdown(h.db, userID, filter)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting tag breakdown for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the statistics per playbook strategy, trades without one are grouped together.
+// it takes the same filters as the statistics
+func (h *StatisticsHandlers) GetStrategyBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := models.GetStrategyBreakdown(h.db, userID, filter)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting strategy breakdown for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler comparing the trades that followed their strategy's checklist with the ones that broke
+// it, overall and per rule. it takes the same filters as the statistics
+func (h *StatisticsHandlers) GetAdherenceReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := models.GetAdherenceReport(h.db, userID, filter)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting checklist adherence for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the calendar of daily P&L of ?month=YYYY-MM, by the day trades were closed in the
+// user's timezone. it takes the same filters as the statistics
+func (h *StatisticsHandlers) GetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := models.GetCalendar(h.db, userID, filter, r.URL.Query().Get("month"))
+	if errors.Is(err, models.ErrInvalidMonth) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting calendar for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve calendar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(calendar); err != nil {
+		log.Printf("Error encoding calendar: %+v", err)
+		http.Error(w, "Failed to encode calendar", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the performance by ?group_by=ticker (the default) or root_symbol and by direction.
+// ?sort_by= takes any of the numbers in the report or symbol, with ?sort_desc=true to reverse it
+func (h *StatisticsHandlers) GetSymbolBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := models.GetSymbolBreakdown(h.db, userID, filter, r.URL.Query().Get("group_by"))
+	if errors.Is(err, models.ErrUnknownSymbolGroup) || errors.Is(err, models.ErrUnknownSortField) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting symbol breakdown for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(breakdown); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the MFE/MAE report, the entry and exit efficiency of every trade with a highest
+// and lowest price and the MAE of the winners against the losers
+func (h *StatisticsHandlers) GetExcursionReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := models.GetExcursionReport(h.db, userID, filter)
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting excursion report for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the suggested stop distance of every root symbol. ?winners_kept=0.9 lets the stop
+// take out the worst 10% of the winners, by default it keeps them all
+func (h *StatisticsHandlers) GetStopSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	winnersKept := 1.0
+	if param := r.URL.Query().Get("winners_kept"); param != "" {
+		if winnersKept, err = strconv.ParseFloat(param, 64); err != nil {
+			http.Error(w, "Invalid winners_kept parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := models.GetStopSuggestions(h.db, userID, filter, winnersKept)
+	if errors.Is(err, models.ErrInvalidWinnersKept) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, models.ErrMissingFXRate) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting stop suggestions for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(suggestions); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the histogram of R-multiples, ?bucket_size= is the width of a bar in R (0.5 by
+// default)
+func (h *StatisticsHandlers) GetRDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bucketSize := 0.5
+	if param := r.URL.Query().Get("bucket_size"); param != "" {
+		if bucketSize, err = strconv.ParseFloat(param, 64); err != nil {
+			http.Error(w, "Invalid bucket_size parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	distribution, err := models.GetRDistribution(h.db, userID, filter, bucketSize)
+	if errors.Is(err, models.ErrInvalidBucketSize) || errors.Is(err, models.ErrTooManyBuckets) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting R distribution for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(distribution); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the equity curve in R
+func (h *StatisticsHandlers) GetRCurveHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	filter, err := statsFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	curve, err := models.GetRCurve(h.db, userID, filter)
+	if err != nil {
+		log.Printf("Error getting R curve for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve statistics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(curve); err != nil {
+		log.Printf("Error encoding statistics: %+v", err)
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+		return
+	}
+}
\ No newline at end of file
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type StrategyHandlers struct {
+	db *sql.DB
+}
+
+func NewStrategyHandlers(db *sql.DB) *StrategyHandlers {
+	return &StrategyHandlers{db: db}
+}
+
+// handler to add a strategy to the playbook, it's active unless the body says otherwise
+func (h *StrategyHandlers) CreateStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	strategy := models.Strategy{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&strategy); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	strategy.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateStrategy(&strategy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := models.CreateStrategy(h.db, &strategy)
+	if errors.Is(err, models.ErrStrategyNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create strategy: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(strategy); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// handler for the user's playbook, ?active=true leaves out the retired strategies
+func (h *StrategyHandlers) ListStrategiesHandler(w http.ResponseWriter, r *http.Request) {
+	activeOnly := false
+	if param := r.URL.Query().Get("active"); param != "" {
+		value, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "Invalid active parameter", http.StatusBadRequest)
+			return
+		}
+		activeOnly = value
+	}
+
+	strategies, err := models.ListStrategies(h.db, userIDFromContext(r.Context()), activeOnly)
+	if err != nil {
+		http.Error(w, "Failed to retrieve strategies: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(strategies); err != nil {
+		http.Error(w, "Failed to encode strategies", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *StrategyHandlers) GetStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+
+	strategy, err := models.GetStrategy(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Strategy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve strategy: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(strategy); err != nil {
+		http.Error(w, "Failed to encode strategy", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *StrategyHandlers) UpdateStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+
+	strategy := models.Strategy{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&strategy); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	strategy.ID = id
+	strategy.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateStrategy(&strategy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.UpdateStrategy(h.db, &strategy)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Strategy not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrStrategyNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update strategy: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(strategy); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler to delete a strategy, its trades stay in the journal without one. retiring it with
+// active false keeps the link instead
+func (h *StrategyHandlers) DeleteStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid strategy ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteStrategy(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Strategy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete strategy: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -29,8 +30,7 @@ func (h *TagHandlers) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Received tag creation request: %+v", tag)
 
-	// in the future, i'll implement user auth. for now, i'll just use 1 as userid
-	tag.UserID = 1
+	tag.UserID = userIDFromContext(r.Context())
 
 	if err := models.CreateTag(h.db, &tag); err != nil {
 		log.Printf("Error creating tag in database: %v", err)
@@ -48,7 +48,7 @@ func (h *TagHandlers) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TagHandlers) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
-	userID := 1
+	userID := userIDFromContext(r.Context())
 
 	tags, err := models.GetTagsByUserID(h.db, userID)
 	if err != nil {
@@ -70,25 +70,15 @@ func (h *TagHandlers) GetTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := 1
+	userID := userIDFromContext(r.Context())
 
-	// get all tags from a user and find the one that we need
-	tags, err := models.GetTagsByUserID(h.db, userID)
-	if err != nil {
-		http.Error(w, "Failed to retrieve tags: "+err.Error(), http.StatusInternalServerError)
+	foundTag, err := models.GetTag(h.db, userID, id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
 		return
 	}
-
-	var foundTag *models.Tag
-	for _, tag := range tags {
-		if tag.ID == id {
-			foundTag = &tag
-			break
-		}
-	}
-
-	if foundTag == nil {
-		http.Error(w, "Tag not found", http.StatusNotFound)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tag: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -113,9 +103,14 @@ func (h *TagHandlers) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	tag.ID = id
-	tag.UserID = 1
+	tag.UserID = userIDFromContext(r.Context())
 
-	if err := models.UpdateTag(h.db, &tag); err != nil {
+	err = models.UpdateTag(h.db, &tag)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to update tag: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -134,9 +129,14 @@ func (h *TagHandlers) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
 		return
 	}
-	userID := 1
+	userID := userIDFromContext(r.Context())
 
-	if err := models.DeleteTag(h.db, id, userID); err != nil {
+	err = models.DeleteTag(h.db, id, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to delete tag: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -159,7 +159,12 @@ func (h *TagHandlers) AddTagToTradeHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := models.AddTagToTrade(h.db, tradeID, tagID); err != nil {
+	err = models.AddTagToTrade(h.db, userIDFromContext(r.Context()), tradeID, tagID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade or tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to add tag to trade: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -182,7 +187,12 @@ func (h *TagHandlers) RemoveTagFromTradeHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if err := models.RemoveTagFromTrade(h.db, tradeID, tagID); err != nil {
+	err = models.RemoveTagFromTrade(h.db, userIDFromContext(r.Context()), tradeID, tagID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Tag not found on trade", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to remove tag from trade: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -198,7 +208,11 @@ func (h *TagHandlers) GetTradeTagsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	tags, err := models.GetTagsByTradeID(h.db, tradeID)
+	tags, err := models.GetTagsByTradeID(h.db, userIDFromContext(r.Context()), tradeID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve tags: "+err.Error(), http.StatusInternalServerError)
 		return
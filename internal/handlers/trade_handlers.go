@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -30,29 +31,93 @@ func (h *TradeHandlers) AddTradeHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// extract and convert direction to uppercase
+	// options trades send their contracts as a json array of legs
+	var legs []models.OptionLeg
+	if raw := r.FormValue("legs"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &legs); err != nil {
+			http.Error(w, `{"error": "invalid legs"}`, http.StatusBadRequest)
+			return
+		}
+		if err := models.ValidateOptionLegs(legs); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// extract and convert direction to uppercase. options trades get it from their legs
 	direction := strings.ToUpper(r.FormValue("direction"))
-	if direction != "LONG" && direction != "SHORT" {
+	if len(legs) == 0 && direction != "LONG" && direction != "SHORT" {
 		http.Error(w, `{"error": "direction must be LONG or SHORT"}`, http.StatusBadRequest)
 		return
 	}
 
 	// extract trade data from form
 	trade := models.Trade{
+		UserID:       userIDFromContext(r.Context()),
 		Ticker:       r.FormValue("ticker"),
 		Direction:    direction,
+		Status:       r.FormValue("status"),
 		EntryPrice:   parseFloat(r.FormValue("entry_price")),
-		ExitPrice:    parseFloat(r.FormValue("exit_price")),
+		ExitPrice:    parseFloatPtr(r.FormValue("exit_price")),
 		Quantity:     parseFloat(r.FormValue("quantity")),
 		TradeDate:    parseTime(r.FormValue("trade_date")),
 		EntryTime:    parseTime(r.FormValue("entry_time")),
-		ExitTime:     parseTime(r.FormValue("exit_time")),
+		ExitTime:     parseTimePtr(r.FormValue("exit_time")),
 		StopLoss:     parseFloatPtr(r.FormValue("stop_loss")),
 		TakeProfit:   parseFloatPtr(r.FormValue("take_profit")),
 		Commissions:  parseFloatPtr(r.FormValue("commissions")),
 		HighestPrice: parseFloatPtr(r.FormValue("highest_price")),
 		LowestPrice:  parseFloatPtr(r.FormValue("lowest_price")),
 		Notes:        stringPtr(r.FormValue("notes")),
+		Currency:     strings.ToUpper(strings.TrimSpace(r.FormValue("currency"))),
+		Legs:         legs,
+		// the risk planned before the entry, in money or in ticks
+		PlannedRisk:      parseFloatPtr(r.FormValue("planned_risk")),
+		PlannedRiskTicks: parseFloatPtr(r.FormValue("planned_risk_ticks")),
+	}
+	if trade.Currency != "" && len(trade.Currency) != 3 {
+		http.Error(w, `{"error": "currency must be a 3 letter code"}`, http.StatusBadRequest)
+		return
+	}
+	models.SummarizeOptionLegs(&trade)
+	trade.RootSymbol = models.RootSymbol(trade.Ticker, trade.EntryTime)
+
+	if err := models.ValidatePlannedRisk(trade); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	// a trade without an exit is an open position
+	if err := models.ValidateTradeStatus(&trade); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// trades go into the default account unless another one is picked
+	if accountID := r.FormValue("account_id"); accountID != "" {
+		id, err := strconv.Atoi(accountID)
+		if err != nil {
+			http.Error(w, `{"error": "invalid account_id"}`, http.StatusBadRequest)
+			return
+		}
+		trade.AccountID = id
+	} else {
+		id, err := defaultAccountID(h.db, trade.UserID)
+		if err != nil {
+			log.Printf("Error getting default account: %v", err)
+			http.Error(w, `{"error": "failed to add trade"}`, http.StatusInternalServerError)
+			return
+		}
+		trade.AccountID = id
+	}
+
+	// the playbook strategy the trade was taken on, if any
+	if strategyID := r.FormValue("strategy_id"); strategyID != "" {
+		id, err := strconv.Atoi(strategyID)
+		if err != nil {
+			http.Error(w, `{"error": "invalid strategy_id"}`, http.StatusBadRequest)
+			return
+		}
+		trade.StrategyID = &id
 	}
 
 	// if the user uploads a screenshot, handle it. in the future, i'm planning to use AWS S3 for this
@@ -89,6 +154,10 @@ func (h *TradeHandlers) AddTradeHandler(w http.ResponseWriter, r *http.Request)
 
 	// add trade to database
 	id, err := models.AddTrade(h.db, trade)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, `{"error": "account or strategy not found"}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("Error adding trade: %v", err)
 		http.Error(w, `{"error": "failed to add trade"}`, http.StatusInternalServerError)
@@ -96,7 +165,7 @@ func (h *TradeHandlers) AddTradeHandler(w http.ResponseWriter, r *http.Request)
 	}
 	trade.ID = id
 
-	// use calculate and insert trademetrics function
+	// use calculate and insert trademetrics function, open positions don't get any until they're closed
 	err = models.CalculateAndInsertTradeMetrics(h.db, trade)
 	if err != nil {
 		log.Printf("Error calculating trade metrics: %v", err)
@@ -141,6 +210,29 @@ func parseTime(s string) time.Time {
 	return t
 }
 
+func parseTimePtr(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t := parseTime(s)
+	return &t
+}
+
+// intListParam reads a repeated or comma separated list of IDs from the query string
+func intListParam(params []string) ([]int, error) {
+	var ids []int
+	for _, param := range params {
+		for _, raw := range strings.Split(param, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func stringPtr(s string) *string {
 	if s == "" {
 		return nil
@@ -161,7 +253,11 @@ func (h *TradeHandlers) GetTradeHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// get the trade from the database
-	trade, err := models.GetTrade(h.db, idInt)
+	trade, err := models.GetTrade(h.db, userIDFromContext(r.Context()), idInt)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "trade not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "failed to get trade", http.StatusInternalServerError)
 		return
@@ -198,11 +294,76 @@ func (h *TradeHandlers) UpdateTradeHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	trade.ID = idInt
-	if err := models.UpdateTrade(h.db, trade); err != nil {
+	trade.UserID = userIDFromContext(r.Context())
+
+	existing, err := models.GetTrade(h.db, trade.UserID, trade.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "failed to update trade", http.StatusInternalServerError)
 		return
 	}
 
+	// keep the trade in its current account if the body doesn't move it
+	if trade.AccountID == 0 {
+		trade.AccountID = existing.AccountID
+	}
+	trade.Currency = strings.ToUpper(strings.TrimSpace(trade.Currency))
+	if trade.Currency == "" {
+		trade.Currency = existing.Currency
+	}
+	if len(trade.Currency) != 3 {
+		http.Error(w, "currency must be a 3 letter code", http.StatusBadRequest)
+		return
+	}
+
+	// new legs replace the old ones and decide the strategy, without them the trade keeps its legs
+	if trade.Legs != nil {
+		if err := models.ValidateOptionLegs(trade.Legs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		models.SummarizeOptionLegs(&trade)
+	} else if len(existing.Legs) > 0 {
+		// the trade level fields still have to add up to the legs it keeps
+		trade.Legs = existing.Legs
+		models.SummarizeOptionLegs(&trade)
+		trade.Legs = nil
+	}
+
+	trade.RootSymbol = models.RootSymbol(trade.Ticker, trade.EntryTime)
+
+	if err := models.ValidatePlannedRisk(trade); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateTradeStatus(&trade); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.UpdateTrade(h.db, trade.UserID, trade)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to update trade", http.StatusInternalServerError)
+		return
+	}
+
+	// the prices may have changed, and a trade that was closed or reopened gains or loses its metrics
+	if err := models.CalculateAndInsertTradeMetrics(h.db, trade); err != nil {
+		log.Printf("Error calculating trade metrics: %v", err)
+		http.Error(w, "failed to update trade metrics", http.StatusInternalServerError)
+		return
+	}
+	if trade.Legs == nil {
+		trade.Legs = existing.Legs
+	}
+
 	if err := json.NewEncoder(w).Encode(trade); err != nil {
 		http.Error(w, "failed to encode trade", http.StatusInternalServerError)
 		return
@@ -221,7 +382,12 @@ func (h *TradeHandlers) DeleteTradeHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := models.DeleteTrade(h.db, idInt); err != nil {
+	err = models.DeleteTrade(h.db, userIDFromContext(r.Context()), idInt)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "failed to delete trade", http.StatusInternalServerError)
 		return
 	}
@@ -229,62 +395,115 @@ func (h *TradeHandlers) DeleteTradeHandler(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *TradeHandlers) ListTradesHandler(w http.ResponseWriter, r *http.Request) {
-	// default values for filter
-	var filter models.TradeFilter
+// handler for POST /api/trades/{id}/close, which closes an open position at exit_price and
+// exit_time (now if left out) and calculates its metrics
+func (h *TradeHandlers) CloseTradeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid trade ID", http.StatusBadRequest)
+		return
+	}
 
-	// check if request is GET or POST
-	if r.Method == "GET" {
-		// get limit from query string
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			limit, err := strconv.Atoi(limitStr)
-			if err != nil {
-				http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
-				return
-			}
-			filter.Limit = limit
-		}
+	var body struct {
+		ExitPrice    *float64   `json:"exit_price"`
+		ExitTime     *time.Time `json:"exit_time"`
+		Commissions  *float64   `json:"commissions"`
+		HighestPrice *float64   `json:"highest_price"`
+		LowestPrice  *float64   `json:"lowest_price"`
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.ExitPrice == nil {
+		http.Error(w, "exit_price is required", http.StatusBadRequest)
+		return
+	}
+	if *body.ExitPrice < 0 {
+		http.Error(w, "exit_price can't be negative", http.StatusBadRequest)
+		return
+	}
+	if body.ExitTime == nil {
+		now := time.Now()
+		body.ExitTime = &now
+	}
 
-		// get offset from query string
-		if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-			offset, err := strconv.Atoi(offsetStr)
-			if err != nil {
-				http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
-				return
-			}
-			filter.Offset = offset
-		}
+	userID := userIDFromContext(r.Context())
+	trade, err := models.GetTrade(h.db, userID, id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "trade not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to close trade", http.StatusInternalServerError)
+		return
+	}
+	if trade.Status != models.TradeStatusOpen {
+		http.Error(w, models.ErrTradeClosed.Error(), http.StatusConflict)
+		return
+	}
 
-		// get ticker from query string
-		if ticker := r.URL.Query().Get("ticker"); ticker != "" {
-			filter.Ticker = ticker
-		}
+	trade.Status = models.TradeStatusClosed
+	trade.ExitPrice = body.ExitPrice
+	trade.ExitTime = body.ExitTime
+	// fees and excursions are only known for sure once the position is flat
+	if body.Commissions != nil {
+		trade.Commissions = body.Commissions
+	}
+	if body.HighestPrice != nil {
+		trade.HighestPrice = body.HighestPrice
+	}
+	if body.LowestPrice != nil {
+		trade.LowestPrice = body.LowestPrice
+	}
+	if err := models.ValidateTradeStatus(&trade); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// get start date from query string
-		if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
-			startDate, err := time.Parse("2006-01-02", startDateStr)
-			if err != nil {
-				http.Error(w, "Invalid start_date format (use YYYY-MM-DD)", http.StatusBadRequest)
-				return
-			}
-			filter.StartDate = &startDate
-		}
+	// the status change and the metrics go in together, otherwise a failed metrics step
+	// would leave a closed trade that can't be closed again
+	tx, err := h.db.Begin()
+	if err != nil {
+		http.Error(w, "failed to close trade", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-		if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
-			endDate, err := time.Parse("2006-01-02", endDateStr)
-			if err != nil {
-				http.Error(w, "Invalid end_date format (use YYYY-MM-DD)", http.StatusBadRequest)
-				return
-			}
-			filter.EndDate = &endDate
-		}
+	if err := models.UpdateTrade(tx, userID, trade); err != nil {
+		log.Printf("Error closing trade %d: %v", id, err)
+		http.Error(w, "failed to close trade", http.StatusInternalServerError)
+		return
+	}
+	if err := models.CalculateAndInsertTradeMetrics(tx, trade); err != nil {
+		log.Printf("Error calculating trade metrics: %v", err)
+		http.Error(w, "failed to calculate trade metrics", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error closing trade %d: %v", id, err)
+		http.Error(w, "failed to close trade", http.StatusInternalServerError)
+		return
+	}
 
-		// get sort parameters
-		if sortBy := r.URL.Query().Get("sort_by"); sortBy != "" {
-			filter.SortBy = sortBy
-			if r.URL.Query().Get("sort_desc") == "true" {
-				filter.SortDesc = true
-			}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(trade); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+func (h *TradeHandlers) ListTradesHandler(w http.ResponseWriter, r *http.Request) {
+	// default values for filter
+	var filter models.TradeFilter
+
+	// check if request is GET or POST
+	if r.Method == "GET" {
+		var err error
+		filter, err = tradeFilterFromQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 	} else if r.Method == "POST" {
 		// if POST, get filter from request body
@@ -295,7 +514,11 @@ func (h *TradeHandlers) ListTradesHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// get the trades from the database
-	trades, err := models.ListTrades(h.db, filter)
+	trades, err := models.ListTrades(h.db, userIDFromContext(r.Context()), filter)
+	if errors.Is(err, models.ErrUnknownSortField) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch trades: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -306,3 +529,118 @@ func (h *TradeHandlers) ListTradesHandler(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(trades)
 }
+
+// tradeFilterFromQuery reads the trade filters from the query string. the trade list and the
+// statistics take the same ones, so "stats for tag X this month" is the same subset of trades
+// the list would show
+func tradeFilterFromQuery(r *http.Request) (models.TradeFilter, error) {
+	var filter models.TradeFilter
+	query := r.URL.Query()
+
+	// get limit from query string
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return filter, errors.New("Invalid limit parameter")
+		}
+		filter.Limit = limit
+	}
+
+	// get offset from query string
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			return filter, errors.New("Invalid offset parameter")
+		}
+		filter.Offset = offset
+	}
+
+	// get ticker from query string
+	filter.Ticker = query.Get("ticker")
+
+	// ?root_symbol=ES gets every contract month of ES
+	filter.RootSymbol = query.Get("root_symbol")
+
+	if direction := query.Get("direction"); direction != "" {
+		filter.Direction = strings.ToUpper(direction)
+		if filter.Direction != "LONG" && filter.Direction != "SHORT" {
+			return filter, errors.New("Invalid direction parameter, use LONG or SHORT")
+		}
+	}
+
+	// ?status=open for open positions, ?status=closed for finished trades
+	if status := query.Get("status"); status != "" {
+		filter.Status = strings.ToUpper(status)
+		if filter.Status != models.TradeStatusOpen && filter.Status != models.TradeStatusClosed {
+			return filter, errors.New("Invalid status parameter, use open or closed")
+		}
+	}
+
+	// ?tag_id=1&tag_id=2 or ?tag_id=1,2 gets the trades that have both tags,
+	// ?exclude_tag_id=3 leaves out the ones tagged 3
+	var err error
+	if filter.TagIDs, err = intListParam(query["tag_id"]); err != nil {
+		return filter, errors.New("Invalid tag_id parameter")
+	}
+	if filter.ExcludeTagIDs, err = intListParam(query["exclude_tag_id"]); err != nil {
+		return filter, errors.New("Invalid exclude_tag_id parameter")
+	}
+
+	// get start date from query string
+	if startDateStr := query.Get("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return filter, errors.New("Invalid start_date format (use YYYY-MM-DD)")
+		}
+		filter.StartDate = &startDate
+	}
+
+	if endDateStr := query.Get("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return filter, errors.New("Invalid end_date format (use YYYY-MM-DD)")
+		}
+		filter.EndDate = &endDate
+	}
+
+	if minProfit := query.Get("min_profit"); minProfit != "" {
+		value, err := strconv.ParseFloat(minProfit, 64)
+		if err != nil {
+			return filter, errors.New("Invalid min_profit parameter")
+		}
+		filter.MinProfit = &value
+	}
+
+	if maxProfit := query.Get("max_profit"); maxProfit != "" {
+		value, err := strconv.ParseFloat(maxProfit, 64)
+		if err != nil {
+			return filter, errors.New("Invalid max_profit parameter")
+		}
+		filter.MaxProfit = &value
+	}
+
+	accountID, err := accountIDParam(r)
+	if err != nil {
+		return filter, errors.New("Invalid account_id parameter")
+	}
+	filter.AccountID = accountID
+
+	// ?strategy_id=2 gets the trades taken on that strategy
+	if strategyID := query.Get("strategy_id"); strategyID != "" {
+		id, err := strconv.Atoi(strategyID)
+		if err != nil {
+			return filter, errors.New("Invalid strategy_id parameter")
+		}
+		filter.StrategyID = &id
+	}
+
+	// get sort parameters
+	if sortBy := query.Get("sort_by"); sortBy != "" {
+		filter.SortBy = sortBy
+		if query.Get("sort_desc") == "true" {
+			filter.SortDesc = true
+		}
+	}
+
+	return filter, nil
+}
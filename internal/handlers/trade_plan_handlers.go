@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"trading-journal/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TradePlanHandlers struct {
+	db *sql.DB
+}
+
+func NewTradePlanHandlers(db *sql.DB) *TradePlanHandlers {
+	return &TradePlanHandlers{db: db}
+}
+
+// handler to write a plan, trade_id links it to a trade that was already taken
+func (h *TradePlanHandlers) CreateTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	var plan models.TradePlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	plan.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateTradePlan(&plan); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := models.CreateTradePlan(h.db, &plan)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrTradeAlreadyPlanned) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, models.ErrPlanTradeMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// handler for the user's plans, ?linked=true or false picks the ones that were or weren't traded
+func (h *TradePlanHandlers) ListTradePlansHandler(w http.ResponseWriter, r *http.Request) {
+	var linked *bool
+	if param := r.URL.Query().Get("linked"); param != "" {
+		value, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "Invalid linked parameter", http.StatusBadRequest)
+			return
+		}
+		linked = &value
+	}
+
+	plans, err := models.ListTradePlans(h.db, userIDFromContext(r.Context()), linked)
+	if err != nil {
+		http.Error(w, "Failed to retrieve trade plans: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plans); err != nil {
+		http.Error(w, "Failed to encode trade plans", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TradePlanHandlers) GetTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := models.GetTradePlan(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade plan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		http.Error(w, "Failed to encode trade plan", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TradePlanHandlers) UpdateTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+
+	var plan models.TradePlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	plan.ID = id
+	plan.UserID = userIDFromContext(r.Context())
+	if err := models.ValidateTradePlan(&plan); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = models.UpdateTradePlan(h.db, &plan)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade plan not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrPlanTradeMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TradePlanHandlers) DeleteTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.DeleteTradePlan(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade plan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler to link a plan to the trade taken from it
+func (h *TradePlanHandlers) LinkTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+	tradeID, err := strconv.Atoi(chi.URLParam(r, "trade_id"))
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.LinkTradePlan(h.db, userIDFromContext(r.Context()), id, &tradeID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrTradeAlreadyPlanned) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, models.ErrPlanTradeMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to link trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TradePlanHandlers) UnlinkTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+
+	err = models.LinkTradePlan(h.db, userIDFromContext(r.Context()), id, nil)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade plan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to unlink trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handler for how one plan's trade went against the plan
+func (h *TradePlanHandlers) CompareTradePlanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid trade plan ID", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := models.GetTradePlan(h.db, userIDFromContext(r.Context()), id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Trade plan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	comparison, err := models.ComparePlan(h.db, plan)
+	if errors.Is(err, models.ErrPlanNotLinked) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to compare trade plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(comparison); err != nil {
+		http.Error(w, "Failed to encode comparison", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handler for the plan vs execution report of every linked plan
+func (h *TradePlanHandlers) GetPlanReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	report, err := models.GetPlanReport(h.db, userID)
+	if err != nil {
+		log.Printf("Error getting plan report for user %d: %+v", userID, err)
+		http.Error(w, "Failed to retrieve plan report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Failed to encode plan report", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// name of the account trades go into when none is picked
+const DefaultAccountName = "Default"
+
+var (
+	ErrAccountNameTaken = errors.New("an account with this name already exists")
+	ErrAccountHasTrades = errors.New("account still has trades or executions")
+)
+
+type Account struct {
+	ID              int       `json:"id"`
+	UserID          int       `json:"user_id"`
+	Name            string    `json:"name"`
+	Broker          *string   `json:"broker"`
+	Currency        string    `json:"currency"`
+	StartingBalance float64   `json:"starting_balance"`
+	AccountType     string    `json:"account_type"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ValidateAccount fills in defaults and checks the fields the accounts table constrains
+func ValidateAccount(account *Account) error {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" {
+		return errors.New("account name is required")
+	}
+	account.Currency = strings.ToUpper(strings.TrimSpace(account.Currency))
+	if account.Currency == "" {
+		account.Currency = "USD"
+	}
+	if len(account.Currency) != 3 {
+		return errors.New("currency must be a 3 letter code")
+	}
+	account.AccountType = strings.ToLower(account.AccountType)
+	if account.AccountType == "" {
+		account.AccountType = "live"
+	}
+	switch account.AccountType {
+	case "live", "sim", "prop":
+	default:
+		return errors.New("account type must be live, sim or prop")
+	}
+	return nil
+}
+
+func CreateAccount(db DbExecutor, account *Account) error {
+	err := db.QueryRow(`
+		INSERT INTO accounts (user_id, name, broker, currency, starting_balance, account_type)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, account.UserID, account.Name, account.Broker, account.Currency, account.StartingBalance, account.AccountType).
+		Scan(&account.ID, &account.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrAccountNameTaken
+		}
+		log.Printf("Error creating account: %v", err)
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+	return nil
+}
+
+// get an account, as long as it belongs to the user
+func GetAccount(db DbExecutor, userID, id int) (Account, error) {
+	var a Account
+	err := db.QueryRow(`
+		SELECT id, user_id, name, broker, currency, starting_balance, account_type, created_at
+		FROM accounts WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&a.ID, &a.UserID, &a.Name, &a.Broker, &a.Currency, &a.StartingBalance, &a.AccountType, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, fmt.Errorf("account with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return a, fmt.Errorf("failed to get account: %w", err)
+	}
+	return a, nil
+}
+
+// get all accounts for a user
+func ListAccounts(db DbExecutor, userID int) ([]Account, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, broker, currency, starting_balance, account_type, created_at
+		FROM accounts WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.Broker, &a.Currency, &a.StartingBalance, &a.AccountType, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+func UpdateAccount(db DbExecutor, account *Account) error {
+	result, err := db.Exec(`
+		UPDATE accounts
+		SET name = $1, broker = $2, currency = $3, starting_balance = $4, account_type = $5
+		WHERE id = $6 AND user_id = $7
+	`, account.Name, account.Broker, account.Currency, account.StartingBalance, account.AccountType, account.ID, account.UserID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrAccountNameTaken
+		}
+		return fmt.Errorf("failed to update account: %w", err)
+	}
+	return expectAffected(result, "account", account.ID)
+}
+
+// delete an account. accounts that still have trades can't be deleted
+func DeleteAccount(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM accounts WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		// 23503 is postgres' foreign_key_violation, raised by trades and executions still in the account
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrAccountHasTrades
+		}
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	return expectAffected(result, "account", id)
+}
+
+// GetOrCreateAccountByName returns the ID of the user's account with that name, creating it
+// if it doesn't exist yet. used for the default account and for broker accounts found in imports
+func GetOrCreateAccountByName(db DbExecutor, userID int, name string, broker *string) (int, error) {
+	var id int
+	err := db.QueryRow("SELECT id FROM accounts WHERE user_id = $1 AND name = $2", userID, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	account := Account{UserID: userID, Name: name, Broker: broker}
+	if err := ValidateAccount(&account); err != nil {
+		return 0, err
+	}
+	if err := CreateAccount(db, &account); err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// checkAccountOwner returns ErrNotFound unless the account exists and belongs to the user
+func checkAccountOwner(db DbExecutor, userID, accountID int) error {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE id = $1 AND user_id = $2)", accountID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check account: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("account with ID %d %w", accountID, ErrNotFound)
+	}
+	return nil
+}
+
+// 23505 is postgres' unique_violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
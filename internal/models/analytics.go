@@ -3,36 +3,109 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
+var ErrNoTrades = errors.New("no trades found for this user")
+
+// StatsFilter narrows down which trades the statistics are calculated from, with the same
+// filters the trade list takes. sorting and paging don't apply
+type StatsFilter struct {
+	TradeFilter
+	// currency to report in, defaults to the account's currency
+	Currency string
+}
+
+// where clause and args for the account rows a filter covers, like the ledger. alias is the
+// alias of a table with user_id and account_id columns. trades go through tradeWhere
+func (f StatsFilter) where(alias string, userID int) (string, []interface{}) {
+	where := alias + ".user_id = $1"
+	args := []interface{}{userID}
+	if f.AccountID != nil {
+		args = append(args, *f.AccountID)
+		where += fmt.Sprintf(" AND %s.account_id = $%d", alias, len(args))
+	}
+	return where, args
+}
+
+// tradeWhere is where for the closed trades in the trades table that match the trade filter.
+// open positions have no realized P&L, they're valued separately
+func (f StatsFilter) tradeWhere(userID int) (string, []interface{}) {
+	tradeFilter := f.TradeFilter
+	tradeFilter.Status = TradeStatusClosed
+	conditions, args := tradeFilter.conditions("t", []interface{}{userID})
+	return strings.Join(append([]string{"t.user_id = $1"}, conditions...), " AND "), args
+}
+
 type AggregateTradeStats struct {
-	TotalTrades          int     `json:"total_trades"`
-	WinningTrades        int     `json:"winning_trades"`
-	LosingTrades         int     `json:"losing_trades"`
-	WinRate              float64 `json:"win_rate"`
-	AverageProfitLoss    float64 `json:"average_profit_loss"`
-	AverageWinner        float64 `json:"average_winner"`
-	AverageLoser         float64 `json:"average_loser"`
-	LargestWinner        float64 `json:"largest_winner"`
-	LargestLoser         float64 `json:"largest_loser"`
-	AverageHoldingPeriod float64 `json:"average_holding_period"`
-	ProfitFactor         float64 `json:"profit_factor"`
-	ExpectancyPerTrade   float64 `json:"expectancy_per_trade"`
-	MaxDrawdown          float64 `json:"max_drawdown"`
-	CurrentStreak        int     `json:"current_streak"`
-	BreakEvenTrades      int     `json:"break_even_trades"`
+	TotalTrades          int      `json:"total_trades"`
+	WinningTrades        int      `json:"winning_trades"`
+	LosingTrades         int      `json:"losing_trades"`
+	WinRate              float64  `json:"win_rate"`
+	AverageProfitLoss    float64  `json:"average_profit_loss"`
+	AverageWinner        float64  `json:"average_winner"`
+	AverageLoser         float64  `json:"average_loser"`
+	LargestWinner        float64  `json:"largest_winner"`
+	LargestLoser         float64  `json:"largest_loser"`
+	AverageHoldingPeriod float64  `json:"average_holding_period"`
+	ProfitFactor         float64  `json:"profit_factor"`
+	ExpectancyPerTrade   float64  `json:"expectancy_per_trade"`
+	MaxDrawdown          *float64 `json:"max_drawdown"` // percent of peak equity, null without a capital base
+	MaxDrawdownAmount    float64  `json:"max_drawdown_amount"`
+	TotalReturn          *float64 `json:"total_return"` // time weighted, in percent, null without a capital base
+	CurrentStreak        int      `json:"current_streak"`
+	BreakEvenTrades      int      `json:"break_even_trades"`
+	NetProfitLoss        float64  `json:"net_profit_loss"` // realized, closed trades only
+	OpenPositions        int      `json:"open_positions"`
+	UnrealizedProfitLoss float64  `json:"unrealized_profit_loss"` // open positions at their stored mark prices
+	Currency             string   `json:"currency"`
+	// risk adjusted measures, see ExtendedStats
+	Extended ExtendedStats `json:"extended"`
+}
+
+// AccountStats is one account's statistics, for comparing accounts side by side
+type AccountStats struct {
+	Account Account             `json:"account"`
+	Stats   AggregateTradeStats `json:"stats"`
 }
 
-func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
+func GetBasicStats(db *sql.DB, userID int, filter StatsFilter) (AggregateTradeStats, error) {
 	var stats AggregateTradeStats
+	scope, args := filter.tradeWhere(userID)
+
 	var tradeCount int
-	err := db.QueryRow("SELECT COUNT(*) FROM trades WHERE user_id = $1", userID).Scan(&tradeCount)
+	err := db.QueryRow("SELECT COUNT(*) FROM trades t WHERE "+scope, args...).Scan(&tradeCount)
+	if err != nil {
+		return stats, err
+	}
+
+	// open positions are kept apart from everything below, which is realized
+	positions, err := GetOpenPositions(db, userID, filter, nil)
 	if err != nil {
 		return stats, err
 	}
+	stats.OpenPositions = len(positions.Positions)
+	stats.UnrealizedProfitLoss = positions.UnrealizedProfitLoss
 	if tradeCount == 0 {
-		return stats, errors.New("no trades found for this user")
+		if stats.OpenPositions == 0 {
+			return stats, ErrNoTrades
+		}
+		// nothing closed yet, the realized statistics stay zero
+		stats.Currency = positions.Currency
+		return stats, nil
+	}
+
+	// every P&L is converted from the trade's currency with the rate at exit time
+	converted, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return stats, err
 	}
+	stats.Currency = converted.currency
+	filter.Currency = converted.currency
+	args = converted.args
+	metrics := converted.metrics
 
 	// get the total amounts of trades, and win/loss
 	err = db.QueryRow(`
@@ -42,9 +115,8 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 			COUNT(CASE WHEN tm.profit_loss < 0 THEN 1 END) as losing_trades,
 			COUNT(CASE WHEN tm.profit_loss = 0 THEN 1 END) as break_even_trades
 		FROM trades t
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
-	`, userID).Scan(&stats.TotalTrades, &stats.WinningTrades, &stats.LosingTrades, &stats.BreakEvenTrades)
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope, args...).Scan(&stats.TotalTrades, &stats.WinningTrades, &stats.LosingTrades, &stats.BreakEvenTrades)
 	if err != nil {
 		return stats, err
 	}
@@ -57,12 +129,12 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 	// calculate average win/loss and holding period
 	err = db.QueryRow(`
 		SELECT 
-			AVG(tm.profit_loss) as avg_profit_loss,
-			AVG(tm.holding_period_minutes) as avg_holding_period
+			COALESCE(AVG(tm.profit_loss), 0) as avg_profit_loss,
+			COALESCE(AVG(tm.holding_period_minutes), 0) as avg_holding_period,
+			COALESCE(SUM(tm.profit_loss), 0) as net_profit_loss
 		FROM trades t
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
-	`, userID).Scan(&stats.AverageProfitLoss, &stats.AverageHoldingPeriod)
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope, args...).Scan(&stats.AverageProfitLoss, &stats.AverageHoldingPeriod, &stats.NetProfitLoss)
 	if err != nil {
 		return stats, err
 	}
@@ -73,9 +145,8 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 			COALESCE(AVG(CASE WHEN tm.profit_loss > 0 THEN tm.profit_loss END), 0) as avg_winner,
 			COALESCE(AVG(CASE WHEN tm.profit_loss < 0 THEN tm.profit_loss END), 0) as avg_loser
 		FROM trades t
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
-	`, userID).Scan(&stats.AverageWinner, &stats.AverageLoser)
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope, args...).Scan(&stats.AverageWinner, &stats.AverageLoser)
 	if err != nil {
 		return stats, err
 	}
@@ -88,9 +159,8 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 			COALESCE(MIN(tm.profit_loss), 0) as largest_loser
 		FROM trades t
 		-- only for user selected
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
-	`, userID).Scan(&stats.LargestWinner, &stats.LargestLoser)
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope, args...).Scan(&stats.LargestWinner, &stats.LargestLoser)
 	if err != nil {
 		return stats, err
 	}
@@ -110,9 +180,8 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 				ELSE 0
 			END as profit_factor
 		FROM trades t
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
-	`, userID).Scan(&stats.ProfitFactor)
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope, args...).Scan(&stats.ProfitFactor)
 	if err != nil {
 		return stats, err
 	}
@@ -125,11 +194,11 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 	err = db.QueryRow(`
 		SELECT profit_loss > 0
 		FROM trades t
-		JOIN trade_metrics tm ON t.id = tm.trade_id
-		WHERE t.user_id = $1
+		JOIN `+metrics+` tm ON t.id = tm.trade_id
+		WHERE `+scope+`
 		ORDER BY t.exit_time DESC
 		LIMIT 1
-	`, userID).Scan(&isLatestTradeWin)
+	`, args...).Scan(&isLatestTradeWin)
 
 	if err != nil {
 		return stats, err
@@ -142,74 +211,78 @@ func GetBasicStats(db *sql.DB, userID int) (AggregateTradeStats, error) {
 				tm.profit_loss > 0 as is_win, -- true if profit_loss is greater than 0 (winning trade)
 				ROW_NUMBER() OVER (ORDER BY t.exit_time DESC) as row_num  -- numbers trades from newest to oldest
 			FROM trades t
-			JOIN trade_metrics tm ON t.id = tm.trade_id
-			WHERE t.user_id = $1
-			ORDER BY t.exit_time DESC
+			JOIN `+metrics+` tm ON t.id = tm.trade_id
+			WHERE `+scope+`
 		),
 		-- get the win/loss of the first trade
 		first_trade AS (
 			SELECT is_win FROM ranked_trades WHERE row_num = 1
-		),
+		)
 		-- count the number of trades in a row with the same win/loss
 		SELECT COUNT(*)
 		FROM ranked_trades r, first_trade f
 		-- check if first trade aligns with the streak
 		WHERE r.is_win = f.is_win
-		AND r.row_num <= (
+		AND r.row_num <= COALESCE((
 			SELECT MIN(r2.row_num) - 1
 			FROM ranked_trades r2, first_trade f
 			WHERE r2.is_win != f.is_win
 			AND r2.row_num > 1
-		)
-	`, userID).Scan(&stats.CurrentStreak)
+		), (SELECT COUNT(*) FROM ranked_trades)) -- every trade is part of the streak if it never broke
+	`, args...).Scan(&stats.CurrentStreak)
+	if err != nil {
+		return stats, err
+	}
 
 	// apply sign based on win/loss
 	if !isLatestTradeWin {
 		stats.CurrentStreak = -stats.CurrentStreak
 	}
 
-	// calculate max drawdown
-	// 1. calculate the running balance
-	// 2. find the peak balance
-	// 3. calculate the drawdown as a percentage of the peak balance
-	rows, err := db.Query(`
-		-- find running balance by summing up the profit_loss of each trade
-		WITH running_balance AS (
-			SELECT 
-				t.exit_time,
-				tm.profit_loss,
-				SUM(tm.profit_loss) OVER (ORDER BY t.exit_time) as balance  -- use OVER to calculate the running total at every trade
-			FROM trades t
-			JOIN trade_metrics tm ON t.id = tm.trade_id
-			WHERE t.user_id = $1
-			ORDER BY t.exit_time
-		),
-		-- find the peak balance
-		peaks AS (
-			SELECT 
-				exit_time,
-				balance,
-				MAX(balance) OVER (ORDER BY exit_time) as peak
-			FROM running_balance
-		)
-		-- calculate the drawdown as a percentage of the peak balance
-		SELECT 
-			(peak - balance) / NULLIF(peak, 0) * 100 as drawdown_percent
-		FROM peaks
-		ORDER BY drawdown_percent DESC
-		LIMIT 1
-	`, userID)
+	// max drawdown and returns are measured against the account equity, which includes the
+	// starting balance and the cash ledger, not just the running P&L of the trades
+	curve, err := GetEquityCurve(db, userID, filter)
 	if err != nil {
 		return stats, err
 	}
-	defer rows.Close()
+	stats.MaxDrawdown = curve.MaxDrawdownPercent
+	stats.MaxDrawdownAmount = curve.MaxDrawdown
+	stats.TotalReturn = curve.TotalReturn
 
-	if rows.Next() {
-		err = rows.Scan(&stats.MaxDrawdown)
-		if err != nil {
-			return stats, err
-		}
+	rMultiples, err := getRMultiples(db, userID, filter)
+	if err != nil {
+		return stats, err
 	}
+	// the daily ratios go by the user's days, like the calendar and the time buckets
+	timezone, err := userTimezone(db, userID)
+	if err != nil {
+		return stats, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return stats, fmt.Errorf("failed to load timezone %q: %w", timezone, err)
+	}
+	stats.Extended = calculateExtendedStats(stats, curve, rMultiples, location)
 
 	return stats, nil
 }
+
+// GetAccountComparison returns the statistics of each of the user's accounts so they can be
+// compared side by side. accounts without trades get zeroed statistics
+func GetAccountComparison(db *sql.DB, userID int) ([]AccountStats, error) {
+	accounts, err := ListAccounts(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := make([]AccountStats, 0, len(accounts))
+	for _, account := range accounts {
+		accountID := account.ID
+		stats, err := GetBasicStats(db, userID, StatsFilter{TradeFilter: TradeFilter{AccountID: &accountID}})
+		if err != nil && !errors.Is(err, ErrNoTrades) {
+			return nil, fmt.Errorf("failed to get statistics for account %d: %w", account.ID, err)
+		}
+		comparison = append(comparison, AccountStats{Account: account, Stats: stats})
+	}
+	return comparison, nil
+}
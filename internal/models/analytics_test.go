@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBasicStatsWithOnlyOpenPositions(t *testing.T) {
+	db := openTestDB(t)
+	userID, accountID := createTestUser(t, db)
+
+	if _, err := GetBasicStats(db, userID, StatsFilter{}); !errors.Is(err, ErrNoTrades) {
+		t.Fatalf("GetBasicStats() without trades error = %v, want ErrNoTrades", err)
+	}
+
+	entryTime := time.Date(2025, 5, 1, 14, 30, 0, 0, time.UTC)
+	if _, err := AddTrade(db, Trade{UserID: userID, AccountID: accountID, Ticker: "AAPL", Direction: "LONG",
+		Status: TradeStatusOpen, EntryPrice: 100, Quantity: 10, TradeDate: entryTime, EntryTime: entryTime}); err != nil {
+		t.Fatalf("failed to add open trade: %v", err)
+	}
+
+	stats, err := GetBasicStats(db, userID, StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetBasicStats() error = %v", err)
+	}
+	if stats.TotalTrades != 0 || stats.NetProfitLoss != 0 {
+		t.Errorf("realized stats are %d trades netting %v, want zero", stats.TotalTrades, stats.NetProfitLoss)
+	}
+	if stats.OpenPositions != 1 || stats.Currency != "USD" {
+		t.Errorf("got %d open positions in %q, want 1 in USD", stats.OpenPositions, stats.Currency)
+	}
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidMonth = errors.New("invalid month, use YYYY-MM")
+
+// CalendarStats is the performance of the trades closed in one calendar period
+type CalendarStats struct {
+	PerformanceStats
+	Commissions float64 `json:"commissions"`
+}
+
+// CalendarDay is one day of the month, days without a closed trade are all zero
+type CalendarDay struct {
+	Date string `json:"date"` // 2025-06-02
+	CalendarStats
+}
+
+// CalendarWeek is the subtotal of the days of an ISO week that fall in the month
+type CalendarWeek struct {
+	Week      string `json:"week"` // 2025-W23
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	CalendarStats
+}
+
+// Calendar is a month of daily P&L, by the day the trades were closed in the user's timezone
+type Calendar struct {
+	Month    string         `json:"month"` // 2025-06
+	Timezone string         `json:"timezone"`
+	Currency string         `json:"currency"`
+	Total    CalendarStats  `json:"total"`
+	Days     []CalendarDay  `json:"days"`
+	Weeks    []CalendarWeek `json:"weeks"`
+}
+
+// GetCalendar returns the daily P&L of a month (YYYY-MM, the current month when empty) with
+// weekly subtotals, for the closed trades of a filter
+func GetCalendar(db *sql.DB, userID int, filter StatsFilter, month string) (Calendar, error) {
+	calendar := Calendar{Days: []CalendarDay{}, Weeks: []CalendarWeek{}}
+
+	timezone, err := userTimezone(db, userID)
+	if err != nil {
+		return calendar, err
+	}
+	calendar.Timezone = timezone
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return calendar, fmt.Errorf("failed to load timezone %q: %w", timezone, err)
+	}
+
+	var start time.Time
+	if month == "" {
+		now := time.Now().In(location)
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	} else {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return calendar, fmt.Errorf("%w: %q", ErrInvalidMonth, month)
+		}
+		start = time.Date(parsed.Year(), parsed.Month(), 1, 0, 0, 0, 0, location)
+	}
+	end := start.AddDate(0, 1, 0)
+	calendar.Month = start.Format("2006-01")
+
+	filter.ClosedFrom, filter.ClosedBefore = &start, &end
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return calendar, err
+	}
+	calendar.Currency = scope.currency
+
+	local := scope.param(timezone)
+	dayKey, _, err := timeBucketSQL(TimeBucketDay, local)
+	if err != nil {
+		return calendar, err
+	}
+	weekKey, _, err := timeBucketSQL(TimeBucketWeek, local)
+	if err != nil {
+		return calendar, err
+	}
+	days, err := calendarStats(db, scope, dayKey)
+	if err != nil {
+		return calendar, err
+	}
+	weeks, err := calendarStats(db, scope, weekKey)
+	if err != nil {
+		return calendar, err
+	}
+	total, err := calendarStats(db, scope, "''")
+	if err != nil {
+		return calendar, err
+	}
+	calendar.Total = total[""]
+
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		calendar.Days = append(calendar.Days, CalendarDay{Date: date, CalendarStats: days[date]})
+
+		year, number := day.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", year, number)
+		if n := len(calendar.Weeks); n > 0 && calendar.Weeks[n-1].Week == week {
+			calendar.Weeks[n-1].EndDate = date
+			continue
+		}
+		calendar.Weeks = append(calendar.Weeks, CalendarWeek{Week: week, StartDate: date, EndDate: date, CalendarStats: weeks[week]})
+	}
+	return calendar, nil
+}
+
+// calendarStats groups the trades in scope by a key expression, with the commissions converted
+// like the P&L
+func calendarStats(db *sql.DB, scope convertedScope, key string) (map[string]CalendarStats, error) {
+	// built before the query runs, commissions adds an arg
+	query := `
+		WITH keyed AS (
+			SELECT
+				` + key + ` AS period,
+				tm.profit_loss,
+				` + knownRiskRMultiple + ` AS r_multiple,
+				` + scope.commissions() + ` AS commissions
+			FROM trades t
+			JOIN ` + scope.metrics + ` tm ON t.id = tm.trade_id
+			WHERE ` + scope.where + `
+		)
+		SELECT period, ` + performanceColumns + `, COALESCE(SUM(commissions), 0)
+		FROM keyed
+		GROUP BY period
+	`
+	rows, err := db.Query(query, scope.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve calendar: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]CalendarStats)
+	for rows.Next() {
+		var period string
+		var s CalendarStats
+		if err := rows.Scan(append(append([]interface{}{&period}, s.scanDest()...), &s.Commissions)...); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar period: %w", err)
+		}
+		s.finish()
+		stats[period] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar: %w", err)
+	}
+	return stats, nil
+}
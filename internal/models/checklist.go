@@ -0,0 +1,339 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChecklistItem is one rule of a strategy's checklist, e.g. "waited for the retest"
+type ChecklistItem struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	StrategyID int       `json:"strategy_id"`
+	Rule       string    `json:"rule"`
+	Position   int       `json:"position"` // the order the rules are listed in
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ChecklistAnswer is a rule of the checklist of a trade's strategy and whether the trade
+// followed it. Followed is nil until the rule has been answered
+type ChecklistAnswer struct {
+	ItemID   int    `json:"item_id"`
+	Rule     string `json:"rule"`
+	Position int    `json:"position"`
+	Followed *bool  `json:"followed"`
+}
+
+// RuleAdherence is the performance of the trades that followed a rule against the ones that
+// broke it
+type RuleAdherence struct {
+	ItemID       int              `json:"item_id"`
+	StrategyID   int              `json:"strategy_id"`
+	StrategyName string           `json:"strategy_name"`
+	Rule         string           `json:"rule"`
+	Followed     PerformanceStats `json:"followed"`
+	Broken       PerformanceStats `json:"broken"`
+}
+
+// AdherenceReport is the closed trades of a filter split by whether they followed their
+// strategy's checklist. a trade that broke any rule is broken, one that answered every rule
+// as followed is followed, the rest (no strategy, no checklist or not fully answered) are
+// unchecked
+type AdherenceReport struct {
+	Currency  string           `json:"currency"`
+	Followed  PerformanceStats `json:"followed"`
+	Broken    PerformanceStats `json:"broken"`
+	Unchecked PerformanceStats `json:"unchecked"`
+	Rules     []RuleAdherence  `json:"rules"`
+}
+
+// checklistAdherence is followed, broken or unchecked for a trade t. only the rules of the
+// trade's current strategy count, answers to the checklist of a strategy it was moved off of
+// are kept but ignored
+const checklistAdherence = `
+	CASE
+		WHEN EXISTS (
+			SELECT 1 FROM trade_checklist ac JOIN checklist_items ai ON ai.id = ac.item_id
+			WHERE ac.trade_id = t.id AND ai.strategy_id = t.strategy_id AND NOT ac.followed
+		) THEN 'broken'
+		WHEN EXISTS (SELECT 1 FROM checklist_items ai WHERE ai.strategy_id = t.strategy_id)
+			AND NOT EXISTS (
+				SELECT 1 FROM checklist_items ai
+				LEFT JOIN trade_checklist ac ON ac.item_id = ai.id AND ac.trade_id = t.id
+				WHERE ai.strategy_id = t.strategy_id AND ac.followed IS NOT TRUE
+			) THEN 'followed'
+		ELSE 'unchecked'
+	END`
+
+func ValidateChecklistItem(item *ChecklistItem) error {
+	item.Rule = strings.TrimSpace(item.Rule)
+	if item.Rule == "" {
+		return errors.New("rule is required")
+	}
+	if len(item.Rule) > 255 {
+		return errors.New("rule must be at most 255 characters")
+	}
+	return nil
+}
+
+// CreateChecklistItem adds a rule to the checklist of one of the user's strategies
+func CreateChecklistItem(db DbExecutor, item *ChecklistItem) error {
+	if err := checkStrategyOwner(db, item.UserID, item.StrategyID); err != nil {
+		return err
+	}
+	err := db.QueryRow(`
+		INSERT INTO checklist_items (user_id, strategy_id, rule, position)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, item.UserID, item.StrategyID, item.Rule, item.Position).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create checklist item: %w", err)
+	}
+	return nil
+}
+
+// ListChecklistItems returns the checklist of a strategy in order
+func ListChecklistItems(db DbExecutor, userID, strategyID int) ([]ChecklistItem, error) {
+	if err := checkStrategyOwner(db, userID, strategyID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT id, user_id, strategy_id, rule, position, created_at
+		FROM checklist_items
+		WHERE strategy_id = $1 AND user_id = $2
+		ORDER BY position, id
+	`, strategyID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve checklist: %w", err)
+	}
+	defer rows.Close()
+
+	items := []ChecklistItem{}
+	for rows.Next() {
+		var item ChecklistItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.StrategyID, &item.Rule, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan checklist item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating checklist: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateChecklistItem changes the wording or order of a rule, it stays on its strategy
+func UpdateChecklistItem(db DbExecutor, item *ChecklistItem) error {
+	err := db.QueryRow(`
+		UPDATE checklist_items SET rule = $1, position = $2
+		WHERE id = $3 AND strategy_id = $4 AND user_id = $5
+		RETURNING created_at
+	`, item.Rule, item.Position, item.ID, item.StrategyID, item.UserID).Scan(&item.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("checklist item with ID %d %w", item.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update checklist item: %w", err)
+	}
+	return nil
+}
+
+// delete a rule, along with every trade's answer to it
+func DeleteChecklistItem(db DbExecutor, userID, strategyID, id int) error {
+	result, err := db.Exec("DELETE FROM checklist_items WHERE id = $1 AND strategy_id = $2 AND user_id = $3", id, strategyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete checklist item: %w", err)
+	}
+	return expectAffected(result, "checklist item", id)
+}
+
+// GetTradeChecklist returns the checklist of the trade's strategy with the trade's answers,
+// empty for a trade without a strategy
+func GetTradeChecklist(db DbExecutor, userID, tradeID int) ([]ChecklistAnswer, error) {
+	if err := checkTradeOwner(db, userID, tradeID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT ci.id, ci.rule, ci.position, tc.followed
+		FROM trades t
+		JOIN checklist_items ci ON ci.strategy_id = t.strategy_id
+		LEFT JOIN trade_checklist tc ON tc.item_id = ci.id AND tc.trade_id = t.id
+		WHERE t.id = $1 AND t.user_id = $2
+		ORDER BY ci.position, ci.id
+	`, tradeID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve trade checklist: %w", err)
+	}
+	defer rows.Close()
+
+	answers := []ChecklistAnswer{}
+	for rows.Next() {
+		var a ChecklistAnswer
+		if err := rows.Scan(&a.ItemID, &a.Rule, &a.Position, &a.Followed); err != nil {
+			return nil, fmt.Errorf("failed to scan checklist answer: %w", err)
+		}
+		answers = append(answers, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trade checklist: %w", err)
+	}
+	return answers, nil
+}
+
+// AnswerChecklistItem records whether a trade followed a rule, replacing an earlier answer.
+// the trade and the rule have to belong to the user, and the rule to the trade's strategy
+func AnswerChecklistItem(db DbExecutor, userID, tradeID, itemID int, followed bool) error {
+	result, err := db.Exec(`
+		INSERT INTO trade_checklist (trade_id, item_id, followed)
+		SELECT t.id, ci.id, $4
+		FROM trades t, checklist_items ci
+		WHERE t.id = $1 AND t.user_id = $3 AND ci.id = $2 AND ci.user_id = $3 AND ci.strategy_id = t.strategy_id
+		ON CONFLICT (trade_id, item_id) DO UPDATE SET followed = EXCLUDED.followed
+	`, tradeID, itemID, userID, followed)
+	if err != nil {
+		return fmt.Errorf("error answering checklist item: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error answering checklist item: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("trade %d or checklist item %d of its strategy %w", tradeID, itemID, ErrNotFound)
+	}
+	return nil
+}
+
+func RemoveChecklistAnswer(db DbExecutor, userID, tradeID, itemID int) error {
+	result, err := db.Exec(`
+		DELETE FROM trade_checklist tc
+		USING trades t
+		WHERE tc.trade_id = t.id AND tc.trade_id = $1 AND tc.item_id = $2 AND t.user_id = $3
+	`, tradeID, itemID, userID)
+	if err != nil {
+		return fmt.Errorf("error removing checklist answer: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error removing checklist answer: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("answer to checklist item %d on trade %d %w", itemID, tradeID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetAdherenceReport compares the closed trades of a filter that followed their checklist with
+// the ones that broke it, overall and rule by rule
+func GetAdherenceReport(db *sql.DB, userID int, filter StatsFilter) (AdherenceReport, error) {
+	report := AdherenceReport{Rules: []RuleAdherence{}}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return report, err
+	}
+	report.Currency = scope.currency
+
+	rows, err := db.Query(`
+		WITH grouped AS (
+			SELECT
+				`+checklistAdherence+` AS adherence,
+				tm.profit_loss,
+				`+knownRiskRMultiple+` AS r_multiple
+			FROM trades t
+			JOIN `+scope.metrics+` tm ON t.id = tm.trade_id
+			WHERE `+scope.where+`
+		)
+		SELECT adherence, `+performanceColumns+`
+		FROM grouped
+		GROUP BY adherence
+	`, scope.args...)
+	if err != nil {
+		return report, fmt.Errorf("failed to retrieve checklist adherence: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adherence string
+		var stats PerformanceStats
+		if err := rows.Scan(append([]interface{}{&adherence}, stats.scanDest()...)...); err != nil {
+			return report, fmt.Errorf("failed to scan checklist adherence: %w", err)
+		}
+		stats.finish()
+		switch adherence {
+		case "followed":
+			report.Followed = stats
+		case "broken":
+			report.Broken = stats
+		default:
+			report.Unchecked = stats
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("error iterating checklist adherence: %w", err)
+	}
+
+	if report.Rules, err = ruleAdherence(db, scope); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// ruleAdherence splits the answered trades in scope by whether they followed each rule
+func ruleAdherence(db *sql.DB, scope convertedScope) ([]RuleAdherence, error) {
+	rows, err := db.Query(`
+		WITH grouped AS (
+			SELECT
+				ci.id AS item_id, ci.strategy_id, s.name AS strategy_name, ci.rule, ci.position,
+				tc.followed,
+				tm.profit_loss,
+				`+knownRiskRMultiple+` AS r_multiple
+			FROM trades t
+			JOIN `+scope.metrics+` tm ON t.id = tm.trade_id
+			JOIN checklist_items ci ON ci.strategy_id = t.strategy_id
+			JOIN trade_checklist tc ON tc.item_id = ci.id AND tc.trade_id = t.id
+			JOIN strategies s ON s.id = ci.strategy_id
+			WHERE `+scope.where+`
+		)
+		SELECT item_id, strategy_id, strategy_name, rule, followed, `+performanceColumns+`
+		FROM grouped
+		GROUP BY item_id, strategy_id, strategy_name, rule, position, followed
+		ORDER BY strategy_name, position, item_id
+	`, scope.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve rule adherence: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []RuleAdherence{}
+	index := make(map[int]int) // item id to its place in rules
+	for rows.Next() {
+		var rule RuleAdherence
+		var followed bool
+		var stats PerformanceStats
+		dest := append([]interface{}{&rule.ItemID, &rule.StrategyID, &rule.StrategyName, &rule.Rule, &followed},
+			stats.scanDest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan rule adherence: %w", err)
+		}
+		stats.finish()
+
+		i, ok := index[rule.ItemID]
+		if !ok {
+			i = len(rules)
+			index[rule.ItemID] = i
+			rules = append(rules, rule)
+		}
+		if followed {
+			rules[i].Followed = stats
+		} else {
+			rules[i].Broken = stats
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rule adherence: %w", err)
+	}
+	return rules, nil
+}
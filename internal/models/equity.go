@@ -0,0 +1,246 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// EquityPoint is the account equity right after one event on the curve
+type EquityPoint struct {
+	Time            time.Time `json:"time"`
+	Kind            string    `json:"kind"` // start, trade or one of the ledger entry types
+	TradeID         *int      `json:"trade_id,omitempty"`
+	LedgerEntryID   *int      `json:"ledger_entry_id,omitempty"`
+	Amount          float64   `json:"amount"`
+	Equity          float64   `json:"equity"`
+	Drawdown        float64   `json:"drawdown"`
+	DrawdownPercent *float64  `json:"drawdown_percent"` // null without a capital base
+}
+
+// EquityCurve is the time ordered equity of one account, or all of a user's accounts together.
+// returns are measured against the equity, so an account that trades without any capital in
+// it (no starting balance or deposits) has no drawdown percent or total return, they're null
+type EquityCurve struct {
+	Currency           string        `json:"currency"`
+	StartingBalance    float64       `json:"starting_balance"`
+	NetDeposits        float64       `json:"net_deposits"`
+	NetProfitLoss      float64       `json:"net_profit_loss"` // trades, fees and adjustments
+	EndingEquity       float64       `json:"ending_equity"`
+	MaxDrawdown        float64       `json:"max_drawdown"`
+	MaxDrawdownPercent *float64      `json:"max_drawdown_percent"`
+	TotalReturn        *float64      `json:"total_return"` // time weighted, in percent
+	Points             []EquityPoint `json:"points"`
+}
+
+type equityEvent struct {
+	time          time.Time
+	kind          string
+	tradeID       *int
+	ledgerEntryID *int
+	amount        float64
+	cashFlow      bool
+}
+
+// GetEquityCurve combines the starting balance, the cash ledger and the P&L of closed trades
+// into an equity curve. a root symbol filter only narrows down the trades, the balance and
+// ledger still belong to the whole account
+func GetEquityCurve(db *sql.DB, userID int, filter StatsFilter) (EquityCurve, error) {
+	accountWhere := "user_id = $1"
+	accountArgs := []interface{}{userID}
+	if filter.AccountID != nil {
+		if err := checkAccountOwner(db, userID, *filter.AccountID); err != nil {
+			return EquityCurve{}, err
+		}
+		accountWhere += " AND id = $2"
+		accountArgs = append(accountArgs, *filter.AccountID)
+	}
+
+	// everything is converted into the reporting currency
+	currency, err := reportingCurrency(db, userID, filter)
+	if err != nil {
+		return EquityCurve{}, err
+	}
+
+	rows, err := db.Query("SELECT id, currency, starting_balance, created_at FROM accounts WHERE "+accountWhere, accountArgs...)
+	if err != nil {
+		return EquityCurve{}, fmt.Errorf("failed to get starting balance: %w", err)
+	}
+	type accountBalance struct {
+		id        int
+		currency  string
+		balance   float64
+		createdAt time.Time
+	}
+	var balances []accountBalance
+	for rows.Next() {
+		var b accountBalance
+		if err := rows.Scan(&b.id, &b.currency, &b.balance, &b.createdAt); err != nil {
+			rows.Close()
+			return EquityCurve{}, fmt.Errorf("failed to scan starting balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return EquityCurve{}, fmt.Errorf("error iterating accounts: %w", err)
+	}
+
+	var startingBalance float64
+	var openedAt time.Time
+	accountCurrency := make(map[int]string)
+	for _, b := range balances {
+		accountCurrency[b.id] = b.currency
+		balance, err := ConvertAmount(db, userID, b.balance, b.currency, currency, b.createdAt)
+		if err != nil {
+			return EquityCurve{}, err
+		}
+		startingBalance += balance
+		if openedAt.IsZero() || b.createdAt.Before(openedAt) {
+			openedAt = b.createdAt
+		}
+	}
+
+	var events []equityEvent
+
+	scope, args := filter.where("l", userID)
+	entries, err := queryLedgerEntries(db, `
+		SELECT l.id, l.user_id, l.account_id, l.entry_type, l.amount, l.occurred_at, l.notes, l.created_at
+		FROM ledger_entries l
+		WHERE `+scope+`
+		ORDER BY l.occurred_at, l.id
+	`, args...)
+	if err != nil {
+		return EquityCurve{}, err
+	}
+	for _, e := range entries {
+		id := e.ID
+		amount, err := ConvertAmount(db, userID, e.SignedAmount(), accountCurrency[e.AccountID], currency, e.OccurredAt)
+		if err != nil {
+			return EquityCurve{}, err
+		}
+		events = append(events, equityEvent{
+			time:          e.OccurredAt,
+			kind:          e.EntryType,
+			ledgerEntryID: &id,
+			amount:        amount,
+			cashFlow:      e.IsCashFlow(),
+		})
+	}
+
+	scope, args = filter.tradeWhere(userID)
+	args = append(args, currency)
+	if err := checkFXRates(db, scope, args, currency); err != nil {
+		return EquityCurve{}, err
+	}
+	rows, err = db.Query(`
+		SELECT t.id, t.exit_time, tm.profit_loss
+		FROM trades t
+		JOIN `+convertedMetrics(len(args))+` tm ON t.id = tm.trade_id
+		WHERE `+scope+`
+		ORDER BY t.exit_time, t.id
+	`, args...)
+	if err != nil {
+		return EquityCurve{}, fmt.Errorf("failed to retrieve trade P&L: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var exitTime time.Time
+		var profitLoss float64
+		if err := rows.Scan(&id, &exitTime, &profitLoss); err != nil {
+			return EquityCurve{}, fmt.Errorf("failed to scan trade P&L: %w", err)
+		}
+		events = append(events, equityEvent{time: exitTime, kind: "trade", tradeID: &id, amount: profitLoss})
+	}
+	if err := rows.Err(); err != nil {
+		return EquityCurve{}, fmt.Errorf("error iterating trade P&L: %w", err)
+	}
+
+	// ledger entries were added first, so on a tie the cash moves before the trade closes
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Before(events[j].time)
+	})
+
+	start := openedAt
+	if len(events) > 0 && (start.IsZero() || events[0].time.Before(start)) {
+		start = events[0].time
+	}
+	curve := buildEquityCurve(startingBalance, start, events)
+	curve.Currency = currency
+	return curve, nil
+}
+
+// buildEquityCurve walks the events in order. deposits and withdrawals move the peak along with
+// the equity so they never show up as drawdown or returns, everything else is performance.
+// the drawdown percent comes from the time weighted growth, so a withdrawal after a loss
+// doesn't make the drawdown look bigger than it was. a trade closed while there was no equity
+// to measure it against has no return, from then on the percentages are left null
+func buildEquityCurve(startingBalance float64, start time.Time, events []equityEvent) EquityCurve {
+	zero := 0.0
+	curve := EquityCurve{
+		StartingBalance: startingBalance,
+		Points:          []EquityPoint{{Time: start, Kind: "start", Amount: startingBalance, Equity: startingBalance, DrawdownPercent: &zero}},
+	}
+
+	equity := startingBalance
+	peak := startingBalance
+	growth := 1.0
+	peakGrowth := 1.0
+	measurable := true
+	var maxDrawdownPercent float64
+	for _, e := range events {
+		if e.cashFlow {
+			equity += e.amount
+			peak += e.amount
+			curve.NetDeposits += e.amount
+		} else {
+			// chain the return of each period so the size of the account doesn't skew it
+			if equity > 0 {
+				growth *= 1 + e.amount/equity
+				if growth < 0 {
+					growth = 0 // the account was blown, it can't lose more than everything
+				}
+			} else if growth > 0 {
+				measurable = false
+			}
+			equity += e.amount
+			curve.NetProfitLoss += e.amount
+		}
+		if equity > peak {
+			peak = equity
+		}
+		if growth > peakGrowth {
+			peakGrowth = growth
+		}
+
+		point := EquityPoint{
+			Time:          e.time,
+			Kind:          e.kind,
+			TradeID:       e.tradeID,
+			LedgerEntryID: e.ledgerEntryID,
+			Amount:        e.amount,
+			Equity:        equity,
+			Drawdown:      peak - equity,
+		}
+		if point.Drawdown > curve.MaxDrawdown {
+			curve.MaxDrawdown = point.Drawdown
+		}
+		if measurable {
+			drawdownPercent := (1 - growth/peakGrowth) * 100
+			point.DrawdownPercent = &drawdownPercent
+			maxDrawdownPercent = math.Max(maxDrawdownPercent, drawdownPercent)
+		}
+		curve.Points = append(curve.Points, point)
+	}
+
+	curve.EndingEquity = equity
+	if measurable {
+		totalReturn := (growth - 1) * 100
+		curve.MaxDrawdownPercent = &maxDrawdownPercent
+		curve.TotalReturn = &totalReturn
+	}
+	return curve
+}
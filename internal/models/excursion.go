@@ -0,0 +1,304 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+var ErrInvalidWinnersKept = errors.New("winners kept has to be more than 0 and at most 1")
+
+// TradeExcursion is how well a trade used the move it got. MFE and MAE are in money like the
+// P&L, the efficiencies compare them to the P&L before commissions, since that's what the
+// price move made
+type TradeExcursion struct {
+	TradeID         int       `json:"trade_id"`
+	Ticker          string    `json:"ticker"`
+	RootSymbol      string    `json:"root_symbol"`
+	Direction       string    `json:"direction"`
+	ExitTime        time.Time `json:"exit_time"`
+	ProfitLoss      float64   `json:"profit_loss"`
+	MFE             float64   `json:"mfe"`
+	MAE             float64   `json:"mae"`
+	ExitEfficiency  *float64  `json:"exit_efficiency"`  // captured P&L over the MFE, nil without an MFE
+	EntryEfficiency *float64  `json:"entry_efficiency"` // MFE over the whole range, nil without a range
+	LeftOnTable     float64   `json:"left_on_table"`    // MFE that wasn't captured
+
+	entryPrice  float64
+	exitPrice   float64
+	quantity    float64
+	commissions float64
+	rate        float64 // trade currency to the reporting currency
+	maePoints   float64 // MAE as a price distance, per contract or share
+}
+
+// Distribution summarizes a set of values
+type Distribution struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// ExcursionGroup is the excursions of the winners or the losers
+type ExcursionGroup struct {
+	Trades                int          `json:"trades"`
+	MAE                   Distribution `json:"mae"`
+	AverageMFE            float64      `json:"average_mfe"`
+	AverageExitEfficiency float64      `json:"average_exit_efficiency"`
+}
+
+// ExcursionReport is the MFE/MAE analysis of the closed trades of a filter. only trades with a
+// highest and lowest price have excursions. options trades don't either, their highs and lows
+// are the underlying's. both are counted as untracked
+type ExcursionReport struct {
+	Currency               string           `json:"currency"`
+	Untracked              int              `json:"untracked"`
+	AverageExitEfficiency  float64          `json:"average_exit_efficiency"`
+	AverageEntryEfficiency float64          `json:"average_entry_efficiency"`
+	LeftOnTable            float64          `json:"left_on_table"`
+	Winners                ExcursionGroup   `json:"winners"`
+	Losers                 ExcursionGroup   `json:"losers"`
+	Trades                 []TradeExcursion `json:"trades"`
+}
+
+// StopSuggestion is the tightest stop for a root symbol that would have kept the share of
+// winners asked for. distances are in price points from the entry
+type StopSuggestion struct {
+	RootSymbol     string       `json:"root_symbol"`
+	WinnerMAE      Distribution `json:"winner_mae"`
+	LoserMAE       Distribution `json:"loser_mae"`
+	StopDistance   *float64     `json:"stop_distance"` // nil without winners to go by
+	WinnersStopped int          `json:"winners_stopped"`
+	LosersCut      int          `json:"losers_cut"` // losers the stop would have made smaller
+	// what the stop would have changed the P&L by, in the reporting currency. a stop also
+	// turns some winners and small losers into bigger losers, which counts against it
+	ProfitLossChange float64 `json:"profit_loss_change"`
+}
+
+// GetExcursionReport works out the entry and exit efficiency of the closed trades of a filter
+func GetExcursionReport(db *sql.DB, userID int, filter StatsFilter) (ExcursionReport, error) {
+	report := ExcursionReport{Trades: []TradeExcursion{}}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return report, err
+	}
+	report.Currency = scope.currency
+
+	excursions, untracked, err := getExcursions(db, scope)
+	if err != nil {
+		return report, err
+	}
+	report.Untracked = untracked
+
+	var exitEfficiencies, entryEfficiencies []float64
+	var winners, losers []TradeExcursion
+	for _, e := range excursions {
+		if e.ExitEfficiency != nil {
+			exitEfficiencies = append(exitEfficiencies, *e.ExitEfficiency)
+		}
+		if e.EntryEfficiency != nil {
+			entryEfficiencies = append(entryEfficiencies, *e.EntryEfficiency)
+		}
+		report.LeftOnTable += e.LeftOnTable
+		switch {
+		case e.ProfitLoss > 0:
+			winners = append(winners, e)
+		case e.ProfitLoss < 0:
+			losers = append(losers, e)
+		}
+	}
+	report.AverageExitEfficiency = mean(exitEfficiencies)
+	report.AverageEntryEfficiency = mean(entryEfficiencies)
+	report.Winners = excursionGroup(winners)
+	report.Losers = excursionGroup(losers)
+	report.Trades = excursions
+	return report, nil
+}
+
+// GetStopSuggestions finds the stop distance for every root symbol in the filter that would
+// have kept winnersKept (0.9 for 90%) of its winners, and what it would have done to the P&L
+func GetStopSuggestions(db *sql.DB, userID int, filter StatsFilter, winnersKept float64) ([]StopSuggestion, error) {
+	if winnersKept <= 0 || winnersKept > 1 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWinnersKept, winnersKept)
+	}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	excursions, _, err := getExcursions(db, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// price distances only compare within one instrument
+	byRoot := make(map[string][]TradeExcursion)
+	var roots []string
+	for _, e := range excursions {
+		if _, ok := byRoot[e.RootSymbol]; !ok {
+			roots = append(roots, e.RootSymbol)
+		}
+		byRoot[e.RootSymbol] = append(byRoot[e.RootSymbol], e)
+	}
+	sort.Strings(roots)
+
+	suggestions := []StopSuggestion{}
+	for _, root := range roots {
+		suggestion := StopSuggestion{RootSymbol: root}
+		var winnerMAE, loserMAE []float64
+		for _, e := range byRoot[root] {
+			switch {
+			case e.ProfitLoss > 0:
+				winnerMAE = append(winnerMAE, e.maePoints)
+			case e.ProfitLoss < 0:
+				loserMAE = append(loserMAE, e.maePoints)
+			}
+		}
+		suggestion.WinnerMAE = distribution(winnerMAE)
+		suggestion.LoserMAE = distribution(loserMAE)
+		if len(winnerMAE) == 0 {
+			suggestions = append(suggestions, suggestion)
+			continue
+		}
+
+		instrument, isFutures, err := FindInstrument(db, userID, root)
+		if err != nil {
+			return nil, err
+		}
+		// one tick past the worst drawdown of the last winner kept, so its low doesn't touch it
+		tick := 0.01
+		if isFutures && instrument.TickSize > 0 {
+			tick = instrument.TickSize
+		}
+		sort.Float64s(winnerMAE)
+		kept := winnerMAE[int(math.Ceil(winnersKept*float64(len(winnerMAE))))-1]
+		distance := math.Round((kept+tick)/tick) * tick
+		suggestion.StopDistance = &distance
+
+		for _, e := range byRoot[root] {
+			if e.maePoints < distance {
+				continue
+			}
+			// the trade would have been stopped out at the stop instead of its exit
+			stopPrice := e.entryPrice - distance
+			if e.Direction == "SHORT" {
+				stopPrice = e.entryPrice + distance
+			}
+			atStop := priceProfitLoss(e.Direction, e.entryPrice, stopPrice, e.quantity, instrument, isFutures)
+			atExit := priceProfitLoss(e.Direction, e.entryPrice, e.exitPrice, e.quantity, instrument, isFutures)
+			change := (atStop - atExit) * e.rate
+			suggestion.ProfitLossChange += change
+			if e.ProfitLoss > 0 {
+				suggestion.WinnersStopped++
+			} else if change > 0 {
+				suggestion.LosersCut++
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// getExcursions loads the excursions of the trades in scope that have them, and counts the ones
+// that don't
+func getExcursions(db *sql.DB, scope convertedScope) ([]TradeExcursion, int, error) {
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM trades t JOIN "+scope.metrics+" tm ON t.id = tm.trade_id WHERE "+scope.where,
+		scope.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count trades: %w", err)
+	}
+
+	// built before the query runs, rate adds an arg
+	rate := scope.rate()
+	query := `
+		SELECT
+			t.id, t.ticker, t.root_symbol, UPPER(t.direction), t.exit_time, t.entry_price, t.exit_price,
+			t.quantity, tm.profit_loss, COALESCE(t.commissions, 0) * ` + rate + `, ` + rate + `,
+			COALESCE(tm.mfe, 0) * ` + rate + `, COALESCE(tm.mae, 0) * ` + rate + `,
+			GREATEST(CASE WHEN UPPER(t.direction) = 'SHORT' THEN t.highest_price - t.entry_price
+				ELSE t.entry_price - t.lowest_price END, 0)
+		FROM trades t
+		JOIN ` + scope.metrics + ` tm ON t.id = tm.trade_id
+		WHERE ` + scope.where + `
+			AND t.highest_price IS NOT NULL AND t.lowest_price IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM option_legs ol WHERE ol.trade_id = t.id)
+		ORDER BY t.exit_time, t.id
+	`
+	rows, err := db.Query(query, scope.args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve excursions: %w", err)
+	}
+	defer rows.Close()
+
+	excursions := []TradeExcursion{}
+	for rows.Next() {
+		var e TradeExcursion
+		if err := rows.Scan(&e.TradeID, &e.Ticker, &e.RootSymbol, &e.Direction, &e.ExitTime, &e.entryPrice,
+			&e.exitPrice, &e.quantity, &e.ProfitLoss, &e.commissions, &e.rate, &e.MFE, &e.MAE, &e.maePoints); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan excursion: %w", err)
+		}
+
+		captured := e.ProfitLoss + e.commissions
+		if e.MFE > 0 {
+			efficiency := captured / e.MFE
+			e.ExitEfficiency = &efficiency
+			e.LeftOnTable = math.Max(e.MFE-captured, 0)
+		}
+		if e.MFE+e.MAE > 0 {
+			efficiency := e.MFE / (e.MFE + e.MAE)
+			e.EntryEfficiency = &efficiency
+		}
+		excursions = append(excursions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating excursions: %w", err)
+	}
+	return excursions, total - len(excursions), nil
+}
+
+func excursionGroup(excursions []TradeExcursion) ExcursionGroup {
+	group := ExcursionGroup{Trades: len(excursions)}
+	var mae, mfe, efficiencies []float64
+	for _, e := range excursions {
+		mae = append(mae, e.MAE)
+		mfe = append(mfe, e.MFE)
+		if e.ExitEfficiency != nil {
+			efficiencies = append(efficiencies, *e.ExitEfficiency)
+		}
+	}
+	group.MAE = distribution(mae)
+	group.AverageMFE = mean(mfe)
+	group.AverageExitEfficiency = mean(efficiencies)
+	return group
+}
+
+func distribution(values []float64) Distribution {
+	d := Distribution{Count: len(values)}
+	if len(values) == 0 {
+		return d
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	d.Mean = mean(sorted)
+	d.Median = percentile(sorted, 0.5)
+	d.P75 = percentile(sorted, 0.75)
+	d.P90 = percentile(sorted, 0.9)
+	d.Max = sorted[len(sorted)-1]
+	return d
+}
+
+// percentile interpolates between the closest values of a sorted slice
+func percentile(sorted []float64, p float64) float64 {
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Execution is a single fill. trades can be built up from several of these when
+// scaling in and out of a position
+type Execution struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	AccountID  int       `json:"account_id"`
+	TradeID    *int      `json:"trade_id"`
+	Ticker     string    `json:"ticker"`
+	Side       string    `json:"side"`
+	Quantity   float64   `json:"quantity"`
+	Price      float64   `json:"price"`
+	ExecutedAt time.Time `json:"executed_at"`
+	Fees       float64   `json:"fees"`
+}
+
+// SignedQuantity returns the quantity as positive for buys and negative for sells
+func (e Execution) SignedQuantity() float64 {
+	if e.Side == "SELL" {
+		return -e.Quantity
+	}
+	return e.Quantity
+}
+
+func AddExecution(db DbExecutor, execution Execution) (int, error) {
+	if err := checkAccountOwner(db, execution.UserID, execution.AccountID); err != nil {
+		return 0, err
+	}
+	// executed_at has no time zone, it's stored in UTC like the trade times
+	execution.ExecutedAt = execution.ExecutedAt.UTC()
+
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO executions (user_id, account_id, trade_id, ticker, side, quantity, price, executed_at, fees)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, execution.UserID, execution.AccountID, execution.TradeID, execution.Ticker, execution.Side, execution.Quantity,
+		execution.Price, execution.ExecutedAt, execution.Fees).Scan(&id)
+	if err != nil {
+		log.Printf("Error inserting execution: %v", err)
+		return 0, fmt.Errorf("failed to insert execution: %w", err)
+	}
+	return id, nil
+}
+
+// get every execution for a user, oldest first
+func ListExecutions(db DbExecutor, userID int) ([]Execution, error) {
+	return queryExecutions(db, `
+		SELECT id, user_id, account_id, trade_id, ticker, side, quantity, price, executed_at, fees
+		FROM executions WHERE user_id = $1
+		ORDER BY executed_at, id
+	`, userID)
+}
+
+// get the executions that haven't been grouped into a trade yet
+func GetUnmatchedExecutions(db DbExecutor, userID int) ([]Execution, error) {
+	return queryExecutions(db, `
+		SELECT id, user_id, account_id, trade_id, ticker, side, quantity, price, executed_at, fees
+		FROM executions WHERE user_id = $1 AND trade_id IS NULL
+		ORDER BY executed_at, id
+	`, userID)
+}
+
+// get the fills that make up a trade
+func GetExecutionsByTradeID(db DbExecutor, userID, tradeID int) ([]Execution, error) {
+	if err := checkTradeOwner(db, userID, tradeID); err != nil {
+		return nil, err
+	}
+	return queryExecutions(db, `
+		SELECT id, user_id, account_id, trade_id, ticker, side, quantity, price, executed_at, fees
+		FROM executions WHERE trade_id = $1 AND user_id = $2
+		ORDER BY executed_at, id
+	`, tradeID, userID)
+}
+
+// UpdateExecutionMatch stores which trade an execution belongs to. the quantity and fees
+// are written too because a fill that flips a position gets split between two trades
+func UpdateExecutionMatch(db DbExecutor, userID int, execution Execution) error {
+	result, err := db.Exec(`
+		UPDATE executions SET trade_id = $1, quantity = $2, fees = $3
+		WHERE id = $4 AND user_id = $5
+	`, execution.TradeID, execution.Quantity, execution.Fees, execution.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update execution: %w", err)
+	}
+	return expectAffected(result, "execution", execution.ID)
+}
+
+func DeleteExecution(db DbExecutor, id, userID int) error {
+	result, err := db.Exec("DELETE FROM executions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete execution: %w", err)
+	}
+	return expectAffected(result, "execution", id)
+}
+
+// DeleteTradeExecutions removes the fills of a trade, so a re-import can store them again
+func DeleteTradeExecutions(db DbExecutor, userID, tradeID int) error {
+	if _, err := db.Exec("DELETE FROM executions WHERE trade_id = $1 AND user_id = $2", tradeID, userID); err != nil {
+		return fmt.Errorf("failed to delete trade executions: %w", err)
+	}
+	return nil
+}
+
+func queryExecutions(db DbExecutor, query string, args ...interface{}) ([]Execution, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := []Execution{}
+	for rows.Next() {
+		var e Execution
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AccountID, &e.TradeID, &e.Ticker, &e.Side, &e.Quantity,
+			&e.Price, &e.ExecutedAt, &e.Fees); err != nil {
+			return nil, fmt.Errorf("failed to scan execution row: %w", err)
+		}
+		executions = append(executions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %w", err)
+	}
+	return executions, nil
+}
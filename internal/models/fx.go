@@ -0,0 +1,207 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrMissingFXRate = errors.New("missing fx rate")
+
+// FXRate is how much of the quote currency one unit of the base currency buys at AsOf
+type FXRate struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+	AsOf          time.Time `json:"as_of"`
+}
+
+func ValidateFXRate(rate *FXRate) error {
+	rate.BaseCurrency = strings.ToUpper(strings.TrimSpace(rate.BaseCurrency))
+	rate.QuoteCurrency = strings.ToUpper(strings.TrimSpace(rate.QuoteCurrency))
+	if len(rate.BaseCurrency) != 3 || len(rate.QuoteCurrency) != 3 {
+		return errors.New("currencies must be 3 letter codes")
+	}
+	if rate.BaseCurrency == rate.QuoteCurrency {
+		return errors.New("base and quote currency must be different")
+	}
+	if rate.Rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+	if rate.AsOf.IsZero() {
+		return errors.New("as_of is required")
+	}
+	return nil
+}
+
+// SaveFXRate stores a rate, replacing the rate for the same pair and time if there is one
+func SaveFXRate(db DbExecutor, rate *FXRate) error {
+	rate.AsOf = rate.AsOf.UTC()
+	err := db.QueryRow(`
+		INSERT INTO fx_rates (user_id, base_currency, quote_currency, rate, as_of)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, base_currency, quote_currency, as_of) DO UPDATE SET rate = EXCLUDED.rate
+		RETURNING id
+	`, rate.UserID, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.AsOf).Scan(&rate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save fx rate: %w", err)
+	}
+	return nil
+}
+
+// get the user's fx rates, newest first. base and quote are optional
+func ListFXRates(db DbExecutor, userID int, base, quote string) ([]FXRate, error) {
+	query := "SELECT id, user_id, base_currency, quote_currency, rate, as_of FROM fx_rates WHERE user_id = $1"
+	args := []interface{}{userID}
+	if base != "" {
+		args = append(args, strings.ToUpper(base))
+		query += fmt.Sprintf(" AND base_currency = $%d", len(args))
+	}
+	if quote != "" {
+		args = append(args, strings.ToUpper(quote))
+		query += fmt.Sprintf(" AND quote_currency = $%d", len(args))
+	}
+	query += " ORDER BY as_of DESC, base_currency, quote_currency"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve fx rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []FXRate{}
+	for rows.Next() {
+		var r FXRate
+		if err := rows.Scan(&r.ID, &r.UserID, &r.BaseCurrency, &r.QuoteCurrency, &r.Rate, &r.AsOf); err != nil {
+			return nil, fmt.Errorf("failed to scan fx rate: %w", err)
+		}
+		rates = append(rates, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fx rates: %w", err)
+	}
+	return rates, nil
+}
+
+func DeleteFXRate(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM fx_rates WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete fx rate: %w", err)
+	}
+	return expectAffected(result, "fx rate", id)
+}
+
+// ConvertAmount converts an amount between currencies with the user's rate at the given time
+func ConvertAmount(db DbExecutor, userID int, amount float64, from, to string, at time.Time) (float64, error) {
+	var rate sql.NullFloat64
+	err := db.QueryRow("SELECT fx_rate($1, $2, $3, $4)", userID, strings.ToUpper(from), strings.ToUpper(to), at.UTC()).Scan(&rate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get fx rate: %w", err)
+	}
+	if !rate.Valid {
+		return 0, fmt.Errorf("%w for %s/%s on %s", ErrMissingFXRate, from, to, at.Format("2006-01-02"))
+	}
+	return amount * rate.Float64, nil
+}
+
+// reportingCurrency is the currency statistics are reported in. it's the filter's currency if it
+// has one, otherwise the account's, otherwise the currency most of the user's accounts use
+func reportingCurrency(db DbExecutor, userID int, filter StatsFilter) (string, error) {
+	if filter.Currency != "" {
+		return strings.ToUpper(filter.Currency), nil
+	}
+
+	var currency string
+	var err error
+	if filter.AccountID != nil {
+		err = db.QueryRow("SELECT currency FROM accounts WHERE id = $1 AND user_id = $2", *filter.AccountID, userID).Scan(&currency)
+	} else {
+		err = db.QueryRow(`
+			SELECT currency FROM accounts WHERE user_id = $1
+			GROUP BY currency ORDER BY COUNT(*) DESC, currency LIMIT 1
+		`, userID).Scan(&currency)
+	}
+	if err == sql.ErrNoRows {
+		return "USD", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get reporting currency: %w", err)
+	}
+	return currency, nil
+}
+
+// convertedMetrics is a trade_metrics lookalike with profit_loss converted into the currency in
+// query parameter param, to be joined in place of trade_metrics. r_multiple is a ratio, max_loss
+// and risk_amount only tell whether the risk was known, so they're passed through as is
+func convertedMetrics(param int) string {
+	return fmt.Sprintf(`(
+		SELECT m.trade_id, m.holding_period_minutes, m.r_multiple, m.max_loss, m.risk_amount,
+			m.profit_loss * fx_rate(mt.user_id, mt.currency, $%d, mt.exit_time) AS profit_loss
+		FROM trade_metrics m
+		JOIN trades mt ON mt.id = m.trade_id
+	)`, param)
+}
+
+// convertedScope is the where clause and args for the closed trades a filter covers, with a
+// trade_metrics lookalike that has every P&L in the reporting currency
+type convertedScope struct {
+	where    string
+	args     []interface{}
+	metrics  string
+	currency string
+}
+
+func newConvertedScope(db DbExecutor, userID int, filter StatsFilter) (convertedScope, error) {
+	currency, err := reportingCurrency(db, userID, filter)
+	if err != nil {
+		return convertedScope{}, err
+	}
+	where, args := filter.tradeWhere(userID)
+	args = append(args, currency)
+	if err := checkFXRates(db, where, args, currency); err != nil {
+		return convertedScope{}, err
+	}
+	return convertedScope{where: where, args: args, metrics: convertedMetrics(len(args)), currency: currency}, nil
+}
+
+// param adds a value to the args and returns its placeholder
+func (s *convertedScope) param(value interface{}) string {
+	s.args = append(s.args, value)
+	return fmt.Sprintf("$%d", len(s.args))
+}
+
+// rate is the expression for the rate that converts the amounts of a trade t into the
+// reporting currency
+func (s *convertedScope) rate() string {
+	return "fx_rate(t.user_id, t.currency, " + s.param(s.currency) + ", t.exit_time)"
+}
+
+// commissions is the expression for the commissions of a trade t in the reporting currency
+func (s *convertedScope) commissions() string {
+	return "COALESCE(t.commissions, 0) * " + s.rate()
+}
+
+// checkFXRates returns ErrMissingFXRate naming the first trade in scope that can't be converted
+// into currency. currency has to be the last of args
+func checkFXRates(db DbExecutor, scope string, args []interface{}, currency string) error {
+	var from string
+	var exitTime time.Time
+	err := db.QueryRow(fmt.Sprintf(`
+		SELECT t.currency, t.exit_time
+		FROM trades t
+		WHERE %s AND fx_rate(t.user_id, t.currency, $%d, t.exit_time) IS NULL
+		ORDER BY t.exit_time
+		LIMIT 1
+	`, scope, len(args)), args...).Scan(&from, &exitTime)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check fx rates: %w", err)
+	}
+	return fmt.Errorf("%w for %s/%s on %s", ErrMissingFXRate, from, currency, exitTime.Format("2006-01-02"))
+}
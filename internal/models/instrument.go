@@ -0,0 +1,189 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrInstrumentExists = errors.New("an instrument with this root symbol already exists")
+
+// Instrument is a contract specification. catalog instruments have no user and are shared,
+// a user's own instrument overrides the catalog one with the same root symbol
+type Instrument struct {
+	ID           int       `json:"id"`
+	UserID       *int      `json:"user_id"`
+	RootSymbol   string    `json:"root_symbol"`
+	Name         string    `json:"name"`
+	AssetClass   string    `json:"asset_class"`
+	TickSize     float64   `json:"tick_size"`
+	TickValue    float64   `json:"tick_value"`
+	PointValue   float64   `json:"point_value"`
+	Currency     string    `json:"currency"`
+	Exchange     string    `json:"exchange"`
+	TradingHours *string   `json:"trading_hours"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PriceDiffValue turns a price difference into money for the given quantity, rounded to
+// whole ticks
+func (i Instrument) PriceDiffValue(priceDiff, quantity float64) float64 {
+	numTicks := math.Round(priceDiff / i.TickSize)
+	return numTicks * i.TickValue * quantity
+}
+
+// ValidateInstrument normalises the fields and checks the ones the instruments table constrains
+func ValidateInstrument(instrument *Instrument) error {
+	instrument.RootSymbol = strings.ToUpper(strings.TrimSpace(instrument.RootSymbol))
+	if instrument.RootSymbol == "" {
+		return errors.New("root symbol is required")
+	}
+	if strings.TrimSpace(instrument.Name) == "" {
+		return errors.New("name is required")
+	}
+	switch instrument.AssetClass {
+	case "equity_index", "interest_rate", "energy", "metal", "agriculture", "livestock", "currency", "crypto":
+	default:
+		return errors.New("asset class must be one of equity_index, interest_rate, energy, metal, agriculture, livestock, currency or crypto")
+	}
+	if instrument.TickSize <= 0 || instrument.TickValue <= 0 {
+		return errors.New("tick size and tick value must be greater than 0")
+	}
+	// the point value follows from the tick if it isn't given
+	if instrument.PointValue <= 0 {
+		instrument.PointValue = instrument.TickValue / instrument.TickSize
+	}
+	instrument.Currency = strings.ToUpper(strings.TrimSpace(instrument.Currency))
+	if instrument.Currency == "" {
+		instrument.Currency = "USD"
+	}
+	if len(instrument.Currency) != 3 {
+		return errors.New("currency must be a 3 letter code")
+	}
+	instrument.Exchange = strings.ToUpper(strings.TrimSpace(instrument.Exchange))
+	if instrument.Exchange == "" {
+		return errors.New("exchange is required")
+	}
+	return nil
+}
+
+const instrumentColumns = `id, user_id, root_symbol, name, asset_class, tick_size, tick_value, point_value,
+	currency, exchange, trading_hours, created_at`
+
+func scanInstrument(row interface{ Scan(...interface{}) error }) (Instrument, error) {
+	var i Instrument
+	err := row.Scan(&i.ID, &i.UserID, &i.RootSymbol, &i.Name, &i.AssetClass, &i.TickSize, &i.TickValue, &i.PointValue,
+		&i.Currency, &i.Exchange, &i.TradingHours, &i.CreatedAt)
+	return i, err
+}
+
+// get the catalog plus the user's own instruments. the user's row wins when both have the same root symbol
+func ListInstruments(db DbExecutor, userID int) ([]Instrument, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT ON (root_symbol) `+instrumentColumns+`
+		FROM instruments
+		WHERE user_id IS NULL OR user_id = $1
+		ORDER BY root_symbol, user_id NULLS LAST
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instruments: %w", err)
+	}
+	defer rows.Close()
+
+	instruments := []Instrument{}
+	for rows.Next() {
+		i, err := scanInstrument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan instrument: %w", err)
+		}
+		instruments = append(instruments, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating instruments: %w", err)
+	}
+	return instruments, nil
+}
+
+// get an instrument by ID, either from the catalog or one of the user's own
+func GetInstrument(db DbExecutor, userID, id int) (Instrument, error) {
+	i, err := scanInstrument(db.QueryRow(`
+		SELECT `+instrumentColumns+`
+		FROM instruments WHERE id = $1 AND (user_id IS NULL OR user_id = $2)
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return i, fmt.Errorf("instrument with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return i, fmt.Errorf("failed to get instrument: %w", err)
+	}
+	return i, nil
+}
+
+// FindInstrument looks up the contract spec for a root symbol, preferring the user's own
+// instrument over the catalog. found is false for symbols that aren't futures
+func FindInstrument(db DbExecutor, userID int, rootSymbol string) (Instrument, bool, error) {
+	i, err := scanInstrument(db.QueryRow(`
+		SELECT `+instrumentColumns+`
+		FROM instruments
+		WHERE root_symbol = $1 AND (user_id IS NULL OR user_id = $2)
+		ORDER BY user_id NULLS LAST
+		LIMIT 1
+	`, strings.ToUpper(rootSymbol), userID))
+	if err == sql.ErrNoRows {
+		return i, false, nil
+	}
+	if err != nil {
+		return i, false, fmt.Errorf("failed to look up instrument %s: %w", rootSymbol, err)
+	}
+	return i, true, nil
+}
+
+// CreateInstrument adds an instrument for the user. the catalog itself is only changed by migrations
+func CreateInstrument(db DbExecutor, userID int, instrument *Instrument) error {
+	instrument.UserID = &userID
+	err := db.QueryRow(`
+		INSERT INTO instruments (user_id, root_symbol, name, asset_class, tick_size, tick_value, point_value, currency, exchange, trading_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`, userID, instrument.RootSymbol, instrument.Name, instrument.AssetClass, instrument.TickSize, instrument.TickValue,
+		instrument.PointValue, instrument.Currency, instrument.Exchange, instrument.TradingHours).Scan(&instrument.ID, &instrument.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrInstrumentExists
+		}
+		log.Printf("Error creating instrument: %v", err)
+		return fmt.Errorf("failed to create instrument: %w", err)
+	}
+	return nil
+}
+
+// update one of the user's own instruments, catalog rows can't be changed this way
+func UpdateInstrument(db DbExecutor, userID int, instrument *Instrument) error {
+	instrument.UserID = &userID
+	result, err := db.Exec(`
+		UPDATE instruments
+		SET root_symbol = $1, name = $2, asset_class = $3, tick_size = $4, tick_value = $5, point_value = $6,
+			currency = $7, exchange = $8, trading_hours = $9
+		WHERE id = $10 AND user_id = $11
+	`, instrument.RootSymbol, instrument.Name, instrument.AssetClass, instrument.TickSize, instrument.TickValue,
+		instrument.PointValue, instrument.Currency, instrument.Exchange, instrument.TradingHours, instrument.ID, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrInstrumentExists
+		}
+		return fmt.Errorf("failed to update instrument: %w", err)
+	}
+	return expectAffected(result, "instrument", instrument.ID)
+}
+
+func DeleteInstrument(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM instruments WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete instrument: %w", err)
+	}
+	return expectAffected(result, "instrument", id)
+}
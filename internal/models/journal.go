@@ -0,0 +1,376 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidJournalDate = errors.New("invalid date, use YYYY-MM-DD")
+
+// the grades of a journal day, best first, and what they count as when they're correlated
+var journalGrades = []string{"A", "B", "C", "D", "F"}
+var journalGradeScores = map[string]float64{"A": 4, "B": 3, "C": 2, "D": 1, "F": 0}
+
+// JournalDay is the journal of one trading day, the plan before the open and the review
+// after the close
+type JournalDay struct {
+	ID                int       `json:"id"`
+	UserID            int       `json:"user_id"`
+	Date              string    `json:"date"` // 2025-06-02
+	PreMarketPlan     *string   `json:"pre_market_plan"`
+	MarketBias        *string   `json:"market_bias"`     // bullish, bearish or neutral
+	EmotionalState    *int      `json:"emotional_state"` // 1 for rattled up to 5 for calm and focused
+	PostSessionReview *string   `json:"post_session_review"`
+	Lessons           *string   `json:"lessons"`
+	Grade             *string   `json:"grade"` // A to F
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// JournalDayDetail is a journal day with the trades closed that day in the user's timezone
+type JournalDayDetail struct {
+	JournalDay
+	Timezone string        `json:"timezone"`
+	Currency string        `json:"currency"`
+	Results  CalendarStats `json:"results"`
+	Trades   []Trade       `json:"trades"`
+}
+
+// JournalGroupStats is the results of the traded journal days with the same rating
+type JournalGroupStats struct {
+	Value                  string  `json:"value"`
+	Days                   int     `json:"days"`
+	WinningDays            int     `json:"winning_days"`
+	Trades                 int     `json:"trades"`
+	NetProfitLoss          float64 `json:"net_profit_loss"`
+	AverageDailyProfitLoss float64 `json:"average_daily_profit_loss"`
+}
+
+// JournalCorrelation lines up the process ratings of the journal with the P&L of the same days.
+// the correlations are pearson's r against the day's net P&L, nil with fewer than three rated
+// days or when every day got the same rating
+type JournalCorrelation struct {
+	Currency                  string              `json:"currency"`
+	TradedDays                int                 `json:"traded_days"`   // journal days with closed trades
+	UntradedDays              int                 `json:"untraded_days"` // journal days without
+	EmotionalStateCorrelation *float64            `json:"emotional_state_correlation"`
+	GradeCorrelation          *float64            `json:"grade_correlation"`
+	ByEmotionalState          []JournalGroupStats `json:"by_emotional_state"`
+	ByGrade                   []JournalGroupStats `json:"by_grade"`
+	ByMarketBias              []JournalGroupStats `json:"by_market_bias"`
+}
+
+// ValidateJournalDay normalises the fields and checks the ones the journal_days table constrains
+func ValidateJournalDay(day *JournalDay) error {
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(day.Date))
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidJournalDate, day.Date)
+	}
+	day.Date = date.Format("2006-01-02")
+
+	if day.MarketBias != nil {
+		bias := strings.ToLower(strings.TrimSpace(*day.MarketBias))
+		switch bias {
+		case "bullish", "bearish", "neutral":
+		default:
+			return errors.New("market bias must be bullish, bearish or neutral")
+		}
+		day.MarketBias = &bias
+	}
+	if day.EmotionalState != nil && (*day.EmotionalState < 1 || *day.EmotionalState > 5) {
+		return errors.New("emotional state must be from 1 to 5")
+	}
+	if day.Grade != nil {
+		grade := strings.ToUpper(strings.TrimSpace(*day.Grade))
+		if _, ok := journalGradeScores[grade]; !ok {
+			return errors.New("grade must be A, B, C, D or F")
+		}
+		day.Grade = &grade
+	}
+	return nil
+}
+
+// SaveJournalDay writes the journal of a day, replacing what the day had
+func SaveJournalDay(db DbExecutor, day *JournalDay) error {
+	err := db.QueryRow(`
+		INSERT INTO journal_days (user_id, journal_date, pre_market_plan, market_bias, emotional_state,
+			post_session_review, lessons, grade)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, journal_date) DO UPDATE SET
+			pre_market_plan = EXCLUDED.pre_market_plan,
+			market_bias = EXCLUDED.market_bias,
+			emotional_state = EXCLUDED.emotional_state,
+			post_session_review = EXCLUDED.post_session_review,
+			lessons = EXCLUDED.lessons,
+			grade = EXCLUDED.grade,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`, day.UserID, day.Date, day.PreMarketPlan, day.MarketBias, day.EmotionalState, day.PostSessionReview,
+		day.Lessons, day.Grade).Scan(&day.ID, &day.CreatedAt, &day.UpdatedAt)
+	if err != nil {
+		log.Printf("Error saving journal day: %v", err)
+		return fmt.Errorf("failed to save journal day: %w", err)
+	}
+	return nil
+}
+
+const journalDayColumns = `id, user_id, journal_date, pre_market_plan, market_bias, emotional_state,
+	post_session_review, lessons, grade, created_at, updated_at`
+
+func scanJournalDay(row interface{ Scan(...interface{}) error }) (JournalDay, error) {
+	var d JournalDay
+	var date time.Time
+	err := row.Scan(&d.ID, &d.UserID, &date, &d.PreMarketPlan, &d.MarketBias, &d.EmotionalState,
+		&d.PostSessionReview, &d.Lessons, &d.Grade, &d.CreatedAt, &d.UpdatedAt)
+	d.Date = date.Format("2006-01-02")
+	return d, err
+}
+
+func GetJournalDay(db DbExecutor, userID int, date string) (JournalDay, error) {
+	day, err := scanJournalDay(db.QueryRow("SELECT "+journalDayColumns+" FROM journal_days WHERE user_id = $1 AND journal_date = $2", userID, date))
+	if err == sql.ErrNoRows {
+		return day, fmt.Errorf("journal day %s %w", date, ErrNotFound)
+	}
+	if err != nil {
+		return day, fmt.Errorf("failed to get journal day: %w", err)
+	}
+	return day, nil
+}
+
+// ListJournalDays returns the user's journal days from one date to another, both included and
+// both optional, newest first
+func ListJournalDays(db DbExecutor, userID int, from, to string) ([]JournalDay, error) {
+	query := "SELECT " + journalDayColumns + " FROM journal_days WHERE user_id = $1"
+	args := []interface{}{userID}
+	if from != "" {
+		args = append(args, from)
+		query += " AND journal_date >= $" + strconv.Itoa(len(args))
+	}
+	if to != "" {
+		args = append(args, to)
+		query += " AND journal_date <= $" + strconv.Itoa(len(args))
+	}
+	rows, err := db.Query(query+" ORDER BY journal_date DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve journal days: %w", err)
+	}
+	defer rows.Close()
+
+	days := []JournalDay{}
+	for rows.Next() {
+		day, err := scanJournalDay(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal day: %w", err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating journal days: %w", err)
+	}
+	return days, nil
+}
+
+func DeleteJournalDay(db DbExecutor, userID int, date string) error {
+	result, err := db.Exec("DELETE FROM journal_days WHERE user_id = $1 AND journal_date = $2", userID, date)
+	if err != nil {
+		return fmt.Errorf("failed to delete journal day: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("journal day %s %w", date, ErrNotFound)
+	}
+	return nil
+}
+
+// GetJournalDayDetail returns a journal day with the trades closed that day and their results,
+// in the currency asked for or the account's
+func GetJournalDayDetail(db *sql.DB, userID int, date, currency string) (JournalDayDetail, error) {
+	detail := JournalDayDetail{Trades: []Trade{}}
+	day, err := GetJournalDay(db, userID, date)
+	if err != nil {
+		return detail, err
+	}
+	detail.JournalDay = day
+
+	timezone, err := userTimezone(db, userID)
+	if err != nil {
+		return detail, err
+	}
+	detail.Timezone = timezone
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return detail, fmt.Errorf("failed to load timezone %q: %w", timezone, err)
+	}
+	parsed, err := time.Parse("2006-01-02", day.Date)
+	if err != nil {
+		return detail, fmt.Errorf("%w: %q", ErrInvalidJournalDate, day.Date)
+	}
+	start := time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, location)
+	end := start.AddDate(0, 0, 1)
+
+	filter := StatsFilter{TradeFilter: TradeFilter{ClosedFrom: &start, ClosedBefore: &end}, Currency: currency}
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return detail, err
+	}
+	detail.Currency = scope.currency
+	results, err := calendarStats(db, scope, "''")
+	if err != nil {
+		return detail, err
+	}
+	detail.Results = results[""]
+
+	filter.Status = TradeStatusClosed
+	filter.SortBy = "exit_time"
+	trades, err := ListTrades(db, userID, filter.TradeFilter)
+	if err != nil {
+		return detail, err
+	}
+	if trades != nil {
+		detail.Trades = trades
+	}
+	return detail, nil
+}
+
+// GetJournalCorrelation compares the ratings of the journal days with the results of the trades
+// of a filter that were closed on them
+func GetJournalCorrelation(db *sql.DB, userID int, filter StatsFilter) (JournalCorrelation, error) {
+	correlation := JournalCorrelation{
+		ByEmotionalState: []JournalGroupStats{},
+		ByGrade:          []JournalGroupStats{},
+		ByMarketBias:     []JournalGroupStats{},
+	}
+
+	timezone, err := userTimezone(db, userID)
+	if err != nil {
+		return correlation, err
+	}
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return correlation, err
+	}
+	correlation.Currency = scope.currency
+	dayKey, _, err := timeBucketSQL(TimeBucketDay, scope.param(timezone))
+	if err != nil {
+		return correlation, err
+	}
+	results, err := calendarStats(db, scope, dayKey)
+	if err != nil {
+		return correlation, err
+	}
+
+	// only the journal days in the filter's window, the ones outside it had their trades
+	// filtered out and would count as untraded
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return correlation, fmt.Errorf("failed to load timezone %q: %w", timezone, err)
+	}
+	from, to := journalDateRange(filter.TradeFilter, location)
+	days, err := ListJournalDays(db, userID, from, to)
+	if err != nil {
+		return correlation, err
+	}
+
+	correlateJournalDays(&correlation, days, results)
+	return correlation, nil
+}
+
+// journalDateRange is the first and last journal date of the filter's exit time window in the
+// user's timezone, empty when that side is open
+func journalDateRange(filter TradeFilter, location *time.Location) (from, to string) {
+	if filter.ClosedFrom != nil {
+		from = filter.ClosedFrom.In(location).Format("2006-01-02")
+	}
+	// the window ends before ClosedBefore, so its last day is the one just before
+	if filter.ClosedBefore != nil {
+		to = filter.ClosedBefore.Add(-time.Nanosecond).In(location).Format("2006-01-02")
+	}
+	return from, to
+}
+
+// correlateJournalDays lines up the ratings of the days with the results closed on them, by date
+func correlateJournalDays(correlation *JournalCorrelation, days []JournalDay, results map[string]CalendarStats) {
+	groups := map[string]map[string]*JournalGroupStats{"emotional_state": {}, "grade": {}, "market_bias": {}}
+	add := func(kind, value string, result CalendarStats) {
+		group, ok := groups[kind][value]
+		if !ok {
+			group = &JournalGroupStats{Value: value}
+			groups[kind][value] = group
+		}
+		group.Days++
+		if result.NetProfitLoss > 0 {
+			group.WinningDays++
+		}
+		group.Trades += result.Trades
+		group.NetProfitLoss += result.NetProfitLoss
+	}
+
+	var emotions, emotionResults, grades, gradeResults []float64
+	for _, day := range days {
+		result, ok := results[day.Date]
+		if !ok {
+			correlation.UntradedDays++
+			continue
+		}
+		correlation.TradedDays++
+		if day.EmotionalState != nil {
+			emotions = append(emotions, float64(*day.EmotionalState))
+			emotionResults = append(emotionResults, result.NetProfitLoss)
+			add("emotional_state", strconv.Itoa(*day.EmotionalState), result)
+		}
+		if day.Grade != nil {
+			grades = append(grades, journalGradeScores[*day.Grade])
+			gradeResults = append(gradeResults, result.NetProfitLoss)
+			add("grade", *day.Grade, result)
+		}
+		if day.MarketBias != nil {
+			add("market_bias", *day.MarketBias, result)
+		}
+	}
+	correlation.EmotionalStateCorrelation = pearson(emotions, emotionResults)
+	correlation.GradeCorrelation = pearson(grades, gradeResults)
+
+	// the groups come out in the order of their scale
+	ordered := func(kind string, values []string) []JournalGroupStats {
+		stats := []JournalGroupStats{}
+		for _, value := range values {
+			if group, ok := groups[kind][value]; ok {
+				group.AverageDailyProfitLoss = group.NetProfitLoss / float64(group.Days)
+				stats = append(stats, *group)
+			}
+		}
+		return stats
+	}
+	correlation.ByEmotionalState = ordered("emotional_state", []string{"1", "2", "3", "4", "5"})
+	correlation.ByGrade = ordered("grade", journalGrades)
+	correlation.ByMarketBias = ordered("market_bias", []string{"bullish", "bearish", "neutral"})
+}
+
+// pearson is the correlation coefficient of two series of the same length, nil when it can't
+// mean anything
+func pearson(xs, ys []float64) *float64 {
+	if len(xs) < 3 || len(xs) != len(ys) {
+		return nil
+	}
+	mx, my := mean(xs), mean(ys)
+	var covariance, vx, vy float64
+	for i := range xs {
+		covariance += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return nil
+	}
+	r := covariance / math.Sqrt(vx*vy)
+	return &r
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name   string
+		xs, ys []float64
+		want   float64 // NaN when there's no correlation
+	}{
+		{"perfectly correlated", []float64{1, 2, 3}, []float64{10, 20, 30}, 1},
+		{"perfectly inverse", []float64{1, 2, 3}, []float64{30, 20, 10}, -1},
+		{"hand-worked", []float64{1, 2, 3, 4}, []float64{2, 1, 4, 3}, 0.6},
+		{"fewer than three days", []float64{1, 2}, []float64{10, 20}, math.NaN()},
+		{"the same rating every day", []float64{3, 3, 3}, []float64{10, -20, 30}, math.NaN()},
+		{"series of different lengths", []float64{1, 2, 3}, []float64{10, 20}, math.NaN()},
+	}
+	for _, tt := range tests {
+		got := deref(pearson(tt.xs, tt.ys))
+		if math.IsNaN(got) != math.IsNaN(tt.want) || (!math.IsNaN(got) && !closeTo(got, tt.want)) {
+			t.Errorf("%s: pearson() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJournalDateRange(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	// a week of new york days, the window ends at the start of the 7th
+	from := time.Date(2025, 6, 2, 0, 0, 0, 0, newYork)
+	before := time.Date(2025, 6, 7, 0, 0, 0, 0, newYork)
+	gotFrom, gotTo := journalDateRange(TradeFilter{ClosedFrom: &from, ClosedBefore: &before}, newYork)
+	if gotFrom != "2025-06-02" || gotTo != "2025-06-06" {
+		t.Errorf("journalDateRange() = %s to %s, want 2025-06-02 to 2025-06-06", gotFrom, gotTo)
+	}
+
+	// 2am UTC is still the evening before in new york
+	from = time.Date(2025, 6, 2, 2, 0, 0, 0, time.UTC)
+	gotFrom, gotTo = journalDateRange(TradeFilter{ClosedFrom: &from}, newYork)
+	if gotFrom != "2025-06-01" || gotTo != "" {
+		t.Errorf("journalDateRange() = %q to %q, want 2025-06-01 to an open end", gotFrom, gotTo)
+	}
+}
+
+func TestCorrelateJournalDays(t *testing.T) {
+	rating := func(i int) *int { return &i }
+	text := func(s string) *string { return &s }
+	result := func(trades int, netProfitLoss float64) CalendarStats {
+		return CalendarStats{PerformanceStats: PerformanceStats{Trades: trades, NetProfitLoss: netProfitLoss}}
+	}
+
+	days := []JournalDay{
+		{Date: "2025-06-02", EmotionalState: rating(5), Grade: text("A"), MarketBias: text("bullish")},
+		{Date: "2025-06-03", EmotionalState: rating(4), Grade: text("B"), MarketBias: text("bullish")},
+		{Date: "2025-06-04", EmotionalState: rating(2), Grade: text("D"), MarketBias: text("bearish")},
+		{Date: "2025-06-05", EmotionalState: rating(5), Grade: text("A")}, // no trades that day
+		{Date: "2025-06-06"}, // traded but not rated
+	}
+	results := map[string]CalendarStats{
+		"2025-06-02": result(2, 300),
+		"2025-06-03": result(1, 100),
+		"2025-06-04": result(3, -200),
+		"2025-06-06": result(1, 50),
+		"2025-06-09": result(4, 500), // no journal for it
+	}
+
+	var correlation JournalCorrelation
+	correlateJournalDays(&correlation, days, results)
+
+	if correlation.TradedDays != 4 || correlation.UntradedDays != 1 {
+		t.Errorf("traded %d and untraded %d days, want 4 and 1", correlation.TradedDays, correlation.UntradedDays)
+	}
+	// the ratings (5, 4, 2) and grades (A, B, D) both line up with 300, 100 and -200
+	for name, got := range map[string]*float64{"emotional state": correlation.EmotionalStateCorrelation, "grade": correlation.GradeCorrelation} {
+		if !closeTo(deref(got), 0.9971764649527383) {
+			t.Errorf("%s correlation = %v, want 0.99718", name, deref(got))
+		}
+	}
+
+	wantEmotions := []JournalGroupStats{
+		{Value: "2", Days: 1, Trades: 3, NetProfitLoss: -200, AverageDailyProfitLoss: -200},
+		{Value: "4", Days: 1, WinningDays: 1, Trades: 1, NetProfitLoss: 100, AverageDailyProfitLoss: 100},
+		{Value: "5", Days: 1, WinningDays: 1, Trades: 2, NetProfitLoss: 300, AverageDailyProfitLoss: 300},
+	}
+	if !reflect.DeepEqual(correlation.ByEmotionalState, wantEmotions) {
+		t.Errorf("ByEmotionalState = %+v, want %+v", correlation.ByEmotionalState, wantEmotions)
+	}
+	wantGrades := []JournalGroupStats{
+		{Value: "A", Days: 1, WinningDays: 1, Trades: 2, NetProfitLoss: 300, AverageDailyProfitLoss: 300},
+		{Value: "B", Days: 1, WinningDays: 1, Trades: 1, NetProfitLoss: 100, AverageDailyProfitLoss: 100},
+		{Value: "D", Days: 1, Trades: 3, NetProfitLoss: -200, AverageDailyProfitLoss: -200},
+	}
+	if !reflect.DeepEqual(correlation.ByGrade, wantGrades) {
+		t.Errorf("ByGrade = %+v, want %+v", correlation.ByGrade, wantGrades)
+	}
+	wantBiases := []JournalGroupStats{
+		{Value: "bullish", Days: 2, WinningDays: 2, Trades: 3, NetProfitLoss: 400, AverageDailyProfitLoss: 200},
+		{Value: "bearish", Days: 1, Trades: 3, NetProfitLoss: -200, AverageDailyProfitLoss: -200},
+	}
+	if !reflect.DeepEqual(correlation.ByMarketBias, wantBiases) {
+		t.Errorf("ByMarketBias = %+v, want %+v", correlation.ByMarketBias, wantBiases)
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ledger entry types. deposits and withdrawals move money in and out of the account, fees
+// and adjustments change the balance the same way a trade does
+const (
+	LedgerDeposit    = "deposit"
+	LedgerWithdrawal = "withdrawal"
+	LedgerFee        = "fee"
+	LedgerAdjustment = "adjustment"
+)
+
+// LedgerEntry is a cash movement on an account that isn't a trade
+type LedgerEntry struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	AccountID  int       `json:"account_id"`
+	EntryType  string    `json:"entry_type"`
+	Amount     float64   `json:"amount"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Notes      *string   `json:"notes"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SignedAmount returns how much the entry changes the account balance by
+func (e LedgerEntry) SignedAmount() float64 {
+	switch e.EntryType {
+	case LedgerWithdrawal, LedgerFee:
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// IsCashFlow is true for money the trader moved in or out, which shouldn't count towards returns
+func (e LedgerEntry) IsCashFlow() bool {
+	return e.EntryType == LedgerDeposit || e.EntryType == LedgerWithdrawal
+}
+
+func ValidateLedgerEntry(entry *LedgerEntry) error {
+	entry.EntryType = strings.ToLower(strings.TrimSpace(entry.EntryType))
+	switch entry.EntryType {
+	case LedgerDeposit, LedgerWithdrawal, LedgerFee:
+		if entry.Amount <= 0 {
+			return errors.New("amount must be greater than 0")
+		}
+	case LedgerAdjustment:
+		if entry.Amount == 0 {
+			return errors.New("adjustment amount can't be 0")
+		}
+	default:
+		return errors.New("entry type must be deposit, withdrawal, fee or adjustment")
+	}
+	if entry.OccurredAt.IsZero() {
+		return errors.New("occurred_at is required")
+	}
+	return nil
+}
+
+func AddLedgerEntry(db DbExecutor, entry *LedgerEntry) error {
+	if err := checkAccountOwner(db, entry.UserID, entry.AccountID); err != nil {
+		return err
+	}
+	entry.OccurredAt = entry.OccurredAt.UTC()
+
+	err := db.QueryRow(`
+		INSERT INTO ledger_entries (user_id, account_id, entry_type, amount, occurred_at, notes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, entry.UserID, entry.AccountID, entry.EntryType, entry.Amount, entry.OccurredAt, entry.Notes).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting ledger entry: %v", err)
+		return fmt.Errorf("failed to insert ledger entry: %w", err)
+	}
+	return nil
+}
+
+// get the ledger of one account, oldest first
+func ListLedgerEntries(db DbExecutor, userID, accountID int) ([]LedgerEntry, error) {
+	if err := checkAccountOwner(db, userID, accountID); err != nil {
+		return nil, err
+	}
+	return queryLedgerEntries(db, `
+		SELECT id, user_id, account_id, entry_type, amount, occurred_at, notes, created_at
+		FROM ledger_entries WHERE user_id = $1 AND account_id = $2
+		ORDER BY occurred_at, id
+	`, userID, accountID)
+}
+
+func DeleteLedgerEntry(db DbExecutor, userID, accountID, id int) error {
+	result, err := db.Exec("DELETE FROM ledger_entries WHERE id = $1 AND account_id = $2 AND user_id = $3", id, accountID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ledger entry: %w", err)
+	}
+	return expectAffected(result, "ledger entry", id)
+}
+
+func queryLedgerEntries(db DbExecutor, query string, args ...interface{}) ([]LedgerEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AccountID, &e.EntryType, &e.Amount, &e.OccurredAt, &e.Notes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MarkPrice is the last known price of a ticker (ESM5) or a root symbol (ES), used to value
+// open positions
+type MarkPrice struct {
+	ID     int       `json:"id"`
+	UserID int       `json:"user_id"`
+	Symbol string    `json:"symbol"`
+	Price  float64   `json:"price"`
+	AsOf   time.Time `json:"as_of"`
+}
+
+// OpenPosition is an open trade valued at a mark price. without a mark price the unrealized
+// P&L is unknown and left out
+type OpenPosition struct {
+	Trade                Trade      `json:"trade"`
+	MarkPrice            *float64   `json:"mark_price"`
+	MarkSource           string     `json:"mark_source,omitempty"` // request or stored
+	MarkedAt             *time.Time `json:"marked_at,omitempty"`
+	UnrealizedProfitLoss *float64   `json:"unrealized_profit_loss"` // in the trade's currency
+}
+
+// OpenPositions is every open position in scope, with the total converted into one currency
+type OpenPositions struct {
+	Currency             string         `json:"currency"`
+	UnrealizedProfitLoss float64        `json:"unrealized_profit_loss"`
+	Unmarked             int            `json:"unmarked"` // positions without a mark price, not in the total
+	Positions            []OpenPosition `json:"positions"`
+}
+
+func ValidateMarkPrice(mark *MarkPrice) error {
+	mark.Symbol = strings.ToUpper(strings.TrimSpace(mark.Symbol))
+	if mark.Symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if mark.Price < 0 {
+		return errors.New("price can't be negative")
+	}
+	if mark.AsOf.IsZero() {
+		mark.AsOf = time.Now()
+	}
+	return nil
+}
+
+// SaveMarkPrice stores the mark price of a symbol, replacing the one it had
+func SaveMarkPrice(db DbExecutor, mark *MarkPrice) error {
+	mark.AsOf = mark.AsOf.UTC()
+	err := db.QueryRow(`
+		INSERT INTO mark_prices (user_id, symbol, price, as_of)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, symbol) DO UPDATE SET price = EXCLUDED.price, as_of = EXCLUDED.as_of
+		RETURNING id
+	`, mark.UserID, mark.Symbol, mark.Price, mark.AsOf).Scan(&mark.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save mark price: %w", err)
+	}
+	return nil
+}
+
+func ListMarkPrices(db DbExecutor, userID int) ([]MarkPrice, error) {
+	rows, err := db.Query("SELECT id, user_id, symbol, price, as_of FROM mark_prices WHERE user_id = $1 ORDER BY symbol", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve mark prices: %w", err)
+	}
+	defer rows.Close()
+
+	marks := []MarkPrice{}
+	for rows.Next() {
+		var m MarkPrice
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Symbol, &m.Price, &m.AsOf); err != nil {
+			return nil, fmt.Errorf("failed to scan mark price: %w", err)
+		}
+		marks = append(marks, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating mark prices: %w", err)
+	}
+	return marks, nil
+}
+
+func DeleteMarkPrice(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM mark_prices WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mark price: %w", err)
+	}
+	return expectAffected(result, "mark price", id)
+}
+
+// GetOpenPositions values the user's open trades. marks are prices sent with the request by
+// ticker or root symbol, they win over the stored mark prices. the total is converted into the
+// reporting currency at today's rate
+func GetOpenPositions(db *sql.DB, userID int, filter StatsFilter, marks map[string]float64) (OpenPositions, error) {
+	currency, err := reportingCurrency(db, userID, filter)
+	if err != nil {
+		return OpenPositions{}, err
+	}
+	positions := OpenPositions{Currency: currency, Positions: []OpenPosition{}}
+
+	tradeFilter := filter.TradeFilter
+	tradeFilter.Status = TradeStatusOpen
+	tradeFilter.SortBy, tradeFilter.SortDesc = "entry_time", false
+	tradeFilter.Limit, tradeFilter.Offset = 0, 0
+	// open positions have no P&L to filter on yet
+	tradeFilter.MinProfit, tradeFilter.MaxProfit = nil, nil
+	trades, err := ListTrades(db, userID, tradeFilter)
+	if err != nil {
+		return positions, err
+	}
+	if len(trades) == 0 {
+		return positions, nil
+	}
+
+	stored, err := ListMarkPrices(db, userID)
+	if err != nil {
+		return positions, err
+	}
+	storedBySymbol := make(map[string]MarkPrice, len(stored))
+	for _, m := range stored {
+		storedBySymbol[m.Symbol] = m
+	}
+
+	now := time.Now()
+	for _, trade := range trades {
+		position := OpenPosition{Trade: trade}
+		ticker := strings.ToUpper(trade.Ticker)
+
+		// the exact contract first, then its root
+		for _, symbol := range []string{ticker, trade.RootSymbol} {
+			if price, ok := marks[symbol]; ok {
+				position.MarkPrice = &price
+				position.MarkSource = "request"
+				position.MarkedAt = &now
+				break
+			}
+			if m, ok := storedBySymbol[symbol]; ok {
+				price, asOf := m.Price, m.AsOf
+				position.MarkPrice = &price
+				position.MarkSource = "stored"
+				position.MarkedAt = &asOf
+				break
+			}
+		}
+		if position.MarkPrice == nil {
+			positions.Unmarked++
+			positions.Positions = append(positions.Positions, position)
+			continue
+		}
+
+		instrument, isFutures, err := FindInstrument(db, userID, trade.RootSymbol)
+		if err != nil {
+			return positions, err
+		}
+		profitLoss := priceProfitLoss(trade.Direction, trade.EntryPrice, *position.MarkPrice, trade.Quantity, instrument, isFutures)
+		if trade.Commissions != nil {
+			profitLoss -= *trade.Commissions
+		}
+		position.UnrealizedProfitLoss = &profitLoss
+
+		converted, err := ConvertAmount(db, userID, profitLoss, trade.Currency, currency, now)
+		if err != nil {
+			return positions, err
+		}
+		positions.UnrealizedProfitLoss += converted
+		positions.Positions = append(positions.Positions, position)
+	}
+	return positions, nil
+}
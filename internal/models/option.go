@@ -0,0 +1,348 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// option strategies a trade's legs can be recognised as
+const (
+	StrategySingle        = "single"
+	StrategyVertical      = "vertical"
+	StrategyCalendar      = "calendar"
+	StrategyStraddle      = "straddle"
+	StrategyStrangle      = "strangle"
+	StrategyButterfly     = "butterfly"
+	StrategyIronCondor    = "iron_condor"
+	StrategyIronButterfly = "iron_butterfly"
+	StrategyCustom        = "custom"
+)
+
+// OptionLeg is one option contract in a trade. side is the side the leg was opened with
+type OptionLeg struct {
+	ID          int       `json:"id"`
+	TradeID     int       `json:"trade_id"`
+	Underlying  string    `json:"underlying"`
+	OptionType  string    `json:"option_type"`
+	Strike      float64   `json:"strike"`
+	Expiry      time.Time `json:"expiry"`
+	Side        string    `json:"side"`
+	Quantity    float64   `json:"quantity"`
+	EntryPrice  float64   `json:"entry_price"`
+	ExitPrice   float64   `json:"exit_price"`
+	Multiplier  float64   `json:"multiplier"`
+	Commissions float64   `json:"commissions"`
+	ProfitLoss  float64   `json:"profit_loss"`
+}
+
+// sign is 1 for legs that were bought and -1 for legs that were sold
+func (l OptionLeg) sign() float64 {
+	if l.Side == "SELL" {
+		return -1
+	}
+	return 1
+}
+
+// LegProfitLoss is the realised P&L of the leg after its commissions
+func (l OptionLeg) LegProfitLoss() float64 {
+	return (l.ExitPrice-l.EntryPrice)*l.sign()*l.Quantity*l.Multiplier - l.Commissions
+}
+
+// value of the leg at expiry if the underlying closes at price
+func (l OptionLeg) expiryValue(price float64) float64 {
+	var intrinsic float64
+	if l.OptionType == "CALL" {
+		intrinsic = math.Max(price-l.Strike, 0)
+	} else {
+		intrinsic = math.Max(l.Strike-price, 0)
+	}
+	return intrinsic * l.sign() * l.Quantity * l.Multiplier
+}
+
+// ValidateOptionLegs normalises the legs and checks they can be stored as one trade
+func ValidateOptionLegs(legs []OptionLeg) error {
+	for i := range legs {
+		leg := &legs[i]
+		leg.Underlying = strings.ToUpper(strings.TrimSpace(leg.Underlying))
+		leg.OptionType = strings.ToUpper(leg.OptionType)
+		leg.Side = strings.ToUpper(leg.Side)
+		if leg.Multiplier == 0 {
+			leg.Multiplier = 100
+		}
+
+		if leg.Underlying == "" {
+			return fmt.Errorf("leg %d: underlying is required", i+1)
+		}
+		if leg.Underlying != legs[0].Underlying {
+			return fmt.Errorf("leg %d: all legs must have the same underlying", i+1)
+		}
+		if leg.OptionType != "CALL" && leg.OptionType != "PUT" {
+			return fmt.Errorf("leg %d: option type must be CALL or PUT", i+1)
+		}
+		if leg.Side != "BUY" && leg.Side != "SELL" {
+			return fmt.Errorf("leg %d: side must be BUY or SELL", i+1)
+		}
+		if leg.Strike <= 0 || leg.Quantity <= 0 || leg.Multiplier < 0 {
+			return fmt.Errorf("leg %d: strike, quantity and multiplier must be greater than 0", i+1)
+		}
+		if leg.EntryPrice < 0 || leg.ExitPrice < 0 {
+			return fmt.Errorf("leg %d: prices can't be negative", i+1)
+		}
+		if leg.Expiry.IsZero() {
+			return fmt.Errorf("leg %d: expiry is required", i+1)
+		}
+	}
+	return nil
+}
+
+// SummarizeOptionLegs fills in the trade level fields from the legs. the quantity is the number
+// of spreads and the prices are the net premium per spread, so a credit spread is a SHORT trade
+// that was opened for the credit and closed for the debit paid to buy it back. the commissions
+// are the legs' own, the trade's are only their total
+func SummarizeOptionLegs(trade *Trade) {
+	legs := trade.Legs
+	if len(legs) == 0 {
+		return
+	}
+
+	quantity := legs[0].Quantity
+	for _, leg := range legs[1:] {
+		quantity = math.Min(quantity, leg.Quantity)
+	}
+
+	var netEntry, netExit, commissions float64
+	for _, leg := range legs {
+		netEntry += leg.sign() * leg.EntryPrice * leg.Quantity
+		netExit += leg.sign() * leg.ExitPrice * leg.Quantity
+		commissions += leg.Commissions
+	}
+
+	trade.Ticker = legs[0].Underlying
+	trade.Quantity = quantity
+	trade.Direction = "LONG"
+	if netEntry < 0 {
+		trade.Direction = "SHORT"
+		netEntry, netExit = -netEntry, -netExit
+	}
+	trade.EntryPrice = netEntry / quantity
+	exitPrice := math.Max(netExit/quantity, 0)
+	trade.ExitPrice = &exitPrice
+	trade.Commissions = &commissions
+
+	strategy := DetectOptionStrategy(legs)
+	trade.Strategy = &strategy
+}
+
+// DetectOptionStrategy recognises the common strategies from the shape of the legs
+func DetectOptionStrategy(legs []OptionLeg) string {
+	sorted := append([]OptionLeg(nil), legs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Strike < sorted[j].Strike })
+
+	sameExpiry := true
+	for _, leg := range sorted {
+		if !leg.Expiry.Equal(sorted[0].Expiry) {
+			sameExpiry = false
+		}
+	}
+
+	switch len(sorted) {
+	case 1:
+		return StrategySingle
+	case 2:
+		a, b := sorted[0], sorted[1]
+		if a.Quantity != b.Quantity {
+			return StrategyCustom
+		}
+		if a.OptionType == b.OptionType && a.Side != b.Side {
+			if sameExpiry && a.Strike != b.Strike {
+				return StrategyVertical
+			}
+			if !sameExpiry && a.Strike == b.Strike {
+				return StrategyCalendar
+			}
+		}
+		if a.OptionType != b.OptionType && a.Side == b.Side && sameExpiry {
+			if a.Strike == b.Strike {
+				return StrategyStraddle
+			}
+			return StrategyStrangle
+		}
+	case 3:
+		// 1 x 2 x 1 with the wings on one side and the body on the other
+		low, body, high := sorted[0], sorted[1], sorted[2]
+		if sameExpiry && low.OptionType == body.OptionType && body.OptionType == high.OptionType &&
+			low.Side == high.Side && low.Side != body.Side &&
+			low.Quantity == high.Quantity && body.Quantity == 2*low.Quantity &&
+			body.Strike-low.Strike == high.Strike-body.Strike {
+			return StrategyButterfly
+		}
+	case 4:
+		// a put vertical below a call vertical, both sold or both bought
+		var puts, calls []OptionLeg
+		for _, leg := range sorted {
+			if leg.OptionType == "PUT" {
+				puts = append(puts, leg)
+			} else {
+				calls = append(calls, leg)
+			}
+		}
+		if !sameExpiry || len(puts) != 2 || len(calls) != 2 {
+			return StrategyCustom
+		}
+		for _, leg := range sorted[1:] {
+			if leg.Quantity != sorted[0].Quantity {
+				return StrategyCustom
+			}
+		}
+		if puts[0].Side == puts[1].Side || calls[0].Side == calls[1].Side || puts[1].Strike > calls[0].Strike ||
+			puts[1].Side != calls[0].Side {
+			return StrategyCustom
+		}
+		if puts[1].Strike == calls[0].Strike {
+			return StrategyIronButterfly
+		}
+		return StrategyIronCondor
+	}
+	return StrategyCustom
+}
+
+// OptionPayoffRange returns the best and worst P&L of the legs if they're held to expiry,
+// after commissions. nil means unlimited, or unknown when the legs expire on different dates
+func OptionPayoffRange(legs []OptionLeg) (maxProfit, maxLoss *float64) {
+	if len(legs) == 0 {
+		return nil, nil
+	}
+	for _, leg := range legs {
+		if !leg.Expiry.Equal(legs[0].Expiry) {
+			return nil, nil
+		}
+	}
+
+	var premium, commissions, slope float64
+	for _, leg := range legs {
+		premium += leg.sign() * leg.EntryPrice * leg.Quantity * leg.Multiplier
+		commissions += leg.Commissions
+		// past the highest strike only the calls still change in value
+		if leg.OptionType == "CALL" {
+			slope += leg.sign() * leg.Quantity * leg.Multiplier
+		}
+	}
+
+	// the payoff is piecewise linear with the kinks at the strikes, so the extremes are
+	// at zero, one of the strikes, or off to infinity
+	best, worst := math.Inf(-1), math.Inf(1)
+	prices := []float64{0}
+	for _, leg := range legs {
+		prices = append(prices, leg.Strike)
+	}
+	for _, price := range prices {
+		var payoff float64
+		for _, leg := range legs {
+			payoff += leg.expiryValue(price)
+		}
+		payoff -= premium + commissions
+		best = math.Max(best, payoff)
+		worst = math.Min(worst, payoff)
+	}
+
+	if slope <= 0 {
+		maxProfit = &best
+	}
+	if slope >= 0 {
+		loss := math.Max(-worst, 0)
+		maxLoss = &loss
+	}
+	return maxProfit, maxLoss
+}
+
+// AddOptionLegs stores the legs of a trade. the trade's ownership has to be checked by the caller
+func AddOptionLegs(db DbExecutor, tradeID int, legs []OptionLeg) error {
+	for i := range legs {
+		legs[i].TradeID = tradeID
+		err := db.QueryRow(`
+			INSERT INTO option_legs (trade_id, underlying, option_type, strike, expiry, side, quantity,
+				entry_price, exit_price, multiplier, commissions)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id
+		`, tradeID, legs[i].Underlying, legs[i].OptionType, legs[i].Strike, legs[i].Expiry, legs[i].Side,
+			legs[i].Quantity, legs[i].EntryPrice, legs[i].ExitPrice, legs[i].Multiplier, legs[i].Commissions).Scan(&legs[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert option leg: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceOptionLegs swaps the legs of one of the user's trades for new ones
+func ReplaceOptionLegs(db DbExecutor, userID, tradeID int, legs []OptionLeg) error {
+	if err := checkTradeOwner(db, userID, tradeID); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM option_legs WHERE trade_id = $1", tradeID); err != nil {
+		return fmt.Errorf("failed to delete option legs: %w", err)
+	}
+	return AddOptionLegs(db, tradeID, legs)
+}
+
+// get the legs of one of the user's trades
+func GetOptionLegs(db DbExecutor, userID, tradeID int) ([]OptionLeg, error) {
+	if err := checkTradeOwner(db, userID, tradeID); err != nil {
+		return nil, err
+	}
+	legs, err := getOptionLegs(db, []int{tradeID})
+	if err != nil {
+		return nil, err
+	}
+	return legs[tradeID], nil
+}
+
+// get the legs of several trades at once, keyed by trade ID
+func getOptionLegs(db DbExecutor, tradeIDs []int) (map[int][]OptionLeg, error) {
+	legs := make(map[int][]OptionLeg)
+	if len(tradeIDs) == 0 {
+		return legs, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT id, trade_id, underlying, option_type, strike, expiry, side, quantity, entry_price, exit_price,
+			multiplier, commissions, COALESCE(profit_loss, 0)
+		FROM option_legs WHERE trade_id = ANY($1)
+		ORDER BY trade_id, id
+	`, pq.Array(tradeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve option legs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l OptionLeg
+		if err := rows.Scan(&l.ID, &l.TradeID, &l.Underlying, &l.OptionType, &l.Strike, &l.Expiry, &l.Side, &l.Quantity,
+			&l.EntryPrice, &l.ExitPrice, &l.Multiplier, &l.Commissions, &l.ProfitLoss); err != nil {
+			return nil, fmt.Errorf("failed to scan option leg: %w", err)
+		}
+		legs[l.TradeID] = append(legs[l.TradeID], l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating option legs: %w", err)
+	}
+	return legs, nil
+}
+
+// store the realised P&L of each leg
+func updateOptionLegProfitLoss(db DbExecutor, legs []OptionLeg) error {
+	for _, leg := range legs {
+		if leg.ID == 0 {
+			return errors.New("option leg ID is required")
+		}
+		if _, err := db.Exec("UPDATE option_legs SET profit_loss = $1 WHERE id = $2", leg.ProfitLoss, leg.ID); err != nil {
+			return fmt.Errorf("failed to update option leg: %w", err)
+		}
+	}
+	return nil
+}
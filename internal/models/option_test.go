@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// a bull call spread, bought for 2.50 and sold for 4.00, with a dollar of fees on each leg. it
+// makes 300 less 2 in fees
+func testSpreadLegs() []OptionLeg {
+	expiry := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+	return []OptionLeg{
+		{Underlying: "SPY", OptionType: "CALL", Strike: 500, Expiry: expiry, Side: "BUY", Quantity: 2,
+			EntryPrice: 6, ExitPrice: 9, Multiplier: 100, Commissions: 1},
+		{Underlying: "SPY", OptionType: "CALL", Strike: 510, Expiry: expiry, Side: "SELL", Quantity: 2,
+			EntryPrice: 3.5, ExitPrice: 5, Multiplier: 100, Commissions: 1},
+	}
+}
+
+func TestSummarizeOptionLegs(t *testing.T) {
+	sent := 5.0
+	trade := Trade{Commissions: &sent, Legs: testSpreadLegs()} // the legs win over what a client sent
+	SummarizeOptionLegs(&trade)
+
+	if trade.Direction != "LONG" || trade.Quantity != 2 || !closeTo(trade.EntryPrice, 2.5) || !closeTo(*trade.ExitPrice, 4) {
+		t.Errorf("summarized as %s %v at %v to %v, want LONG 2 at 2.5 to 4", trade.Direction, trade.Quantity,
+			trade.EntryPrice, *trade.ExitPrice)
+	}
+	if trade.Commissions == nil || *trade.Commissions != 2 {
+		t.Errorf("Commissions = %v, want the legs' 2", deref(trade.Commissions))
+	}
+	if trade.Strategy == nil || *trade.Strategy != StrategyVertical {
+		t.Errorf("Strategy = %v, want %s", trade.Strategy, StrategyVertical)
+	}
+}
+
+// the legs' P&L is after their own fees, so the trade's total of them isn't taken off again
+func TestOptionTradeMetricsCountCommissionsOnce(t *testing.T) {
+	db := openTestDB(t)
+	userID, accountID := createTestUser(t, db)
+
+	entryTime := time.Date(2025, 6, 2, 14, 30, 0, 0, time.UTC)
+	exitTime := entryTime.Add(24 * time.Hour)
+	trade := Trade{UserID: userID, AccountID: accountID, Status: TradeStatusClosed, TradeDate: entryTime,
+		EntryTime: entryTime, ExitTime: &exitTime, Legs: testSpreadLegs()}
+	SummarizeOptionLegs(&trade)
+	id, err := AddTrade(db, trade)
+	if err != nil {
+		t.Fatalf("AddTrade() error = %v", err)
+	}
+	trade.ID = id
+	if err := CalculateAndInsertTradeMetrics(db, trade); err != nil {
+		t.Fatalf("CalculateAndInsertTradeMetrics() error = %v", err)
+	}
+
+	var profitLoss float64
+	if err := db.QueryRow("SELECT profit_loss FROM trade_metrics WHERE trade_id = $1", id).Scan(&profitLoss); err != nil {
+		t.Fatalf("failed to read trade metrics: %v", err)
+	}
+	if !closeTo(profitLoss, 298) {
+		t.Errorf("profit_loss = %v, want 298", profitLoss)
+	}
+}
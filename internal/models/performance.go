@@ -0,0 +1,60 @@
+package models
+
+// PerformanceStats is the performance of a group of closed trades, the common part of the
+// breakdowns by time, tag and so on
+type PerformanceStats struct {
+	Trades        int      `json:"trades"`
+	WinningTrades int      `json:"winning_trades"`
+	LosingTrades  int      `json:"losing_trades"`
+	WinRate       float64  `json:"win_rate"` // break-even trades don't count
+	NetProfitLoss float64  `json:"net_profit_loss"`
+	Expectancy    float64  `json:"expectancy"`
+	AverageR      *float64 `json:"average_r"` // nil when no trade in the group had a known risk
+	ProfitFactor  float64  `json:"profit_factor"`
+
+	grossProfit float64
+	grossLoss   float64
+}
+
+// performanceColumns aggregates a group of rows with profit_loss and r_multiple columns into
+// the columns scanned by PerformanceStats.scanDest
+const performanceColumns = `
+	COUNT(*),
+	COUNT(CASE WHEN profit_loss > 0 THEN 1 END),
+	COUNT(CASE WHEN profit_loss < 0 THEN 1 END),
+	COALESCE(SUM(profit_loss), 0),
+	AVG(r_multiple),
+	COALESCE(SUM(CASE WHEN profit_loss > 0 THEN profit_loss END), 0),
+	COALESCE(ABS(SUM(CASE WHEN profit_loss < 0 THEN profit_loss END)), 0)`
+
+// knownRiskRMultiple is the R-multiple of a trade with metrics tm, or NULL when the trade's risk
+// wasn't known, since its R means nothing then
+const knownRiskRMultiple = "CASE WHEN tm.risk_amount > 0 THEN tm.r_multiple END"
+
+func (p *PerformanceStats) scanDest() []interface{} {
+	return []interface{}{&p.Trades, &p.WinningTrades, &p.LosingTrades, &p.NetProfitLoss, &p.AverageR,
+		&p.grossProfit, &p.grossLoss}
+}
+
+// finish works out the ratios once the columns have been scanned
+func (p *PerformanceStats) finish() {
+	decided := p.WinningTrades + p.LosingTrades
+	if decided == 0 {
+		return
+	}
+	p.WinRate = float64(p.WinningTrades) / float64(decided)
+
+	var averageWinner, averageLoser float64
+	if p.WinningTrades > 0 {
+		averageWinner = p.grossProfit / float64(p.WinningTrades)
+	}
+	if p.LosingTrades > 0 {
+		averageLoser = -p.grossLoss / float64(p.LosingTrades)
+	}
+	// the same expectancy as the basic statistics
+	p.Expectancy = p.WinRate*averageWinner + (1-p.WinRate)*averageLoser
+
+	if p.grossLoss > 0 {
+		p.ProfitFactor = p.grossProfit / p.grossLoss
+	}
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidBucketSize = errors.New("bucket size must be greater than 0")
+	ErrTooManyBuckets    = errors.New("too many buckets, use a bigger bucket size")
+)
+
+// the most bars a histogram can have, a tiny bucket size over a wide spread of R would
+// otherwise allocate one for every step in between
+const maxRBuckets = 1000
+
+// RBucket is one bar of the R histogram, the trades with an R from From up to To
+type RBucket struct {
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Trades int     `json:"trades"`
+}
+
+// RDistribution is the spread of the R-multiples of the closed trades of a filter. trades
+// without a known risk have no R, they're counted as untracked
+type RDistribution struct {
+	Trades       int       `json:"trades"`
+	Untracked    int       `json:"untracked"`
+	WinRate      float64   `json:"win_rate"`
+	AverageR     float64   `json:"average_r"` // the expectancy in R
+	AverageWinR  float64   `json:"average_win_r"`
+	AverageLossR float64   `json:"average_loss_r"`
+	MedianR      float64   `json:"median_r"`
+	StdDev       float64   `json:"std_dev"`
+	BucketSize   float64   `json:"bucket_size"`
+	Buckets      []RBucket `json:"buckets"`
+}
+
+// RCurvePoint is the running total of R after a trade
+type RCurvePoint struct {
+	TradeID   int       `json:"trade_id"`
+	Time      time.Time `json:"time"`
+	R         float64   `json:"r"`
+	TotalR    float64   `json:"total_r"`
+	DrawdownR float64   `json:"drawdown_r"` // R given back since the last peak
+}
+
+// RCurve is the equity curve in R, which leaves out position sizing and currencies
+type RCurve struct {
+	TotalR       float64       `json:"total_r"`
+	MaxDrawdownR float64       `json:"max_drawdown_r"`
+	Points       []RCurvePoint `json:"points"`
+}
+
+// rTrade is the R of a closed trade
+type rTrade struct {
+	id        int
+	exitTime  time.Time
+	rMultiple float64
+}
+
+// GetRDistribution groups the R-multiples of the closed trades of a filter into buckets of
+// bucketSize R
+func GetRDistribution(db *sql.DB, userID int, filter StatsFilter, bucketSize float64) (RDistribution, error) {
+	distribution := RDistribution{BucketSize: bucketSize, Buckets: []RBucket{}}
+	if !(bucketSize > 0) || math.IsInf(bucketSize, 0) {
+		return distribution, fmt.Errorf("%w: %v", ErrInvalidBucketSize, bucketSize)
+	}
+
+	trades, err := getRTrades(db, userID, filter)
+	if err != nil {
+		return distribution, err
+	}
+	where, args := filter.tradeWhere(userID)
+	var closed int
+	err = db.QueryRow("SELECT COUNT(*) FROM trades t JOIN trade_metrics tm ON t.id = tm.trade_id WHERE "+where, args...).Scan(&closed)
+	if err != nil {
+		return distribution, fmt.Errorf("failed to count trades: %w", err)
+	}
+	distribution.Trades = len(trades)
+	distribution.Untracked = closed - len(trades)
+	if len(trades) == 0 {
+		return distribution, nil
+	}
+
+	rMultiples := make([]float64, len(trades))
+	var wins, losses []float64
+	for i, trade := range trades {
+		rMultiples[i] = trade.rMultiple
+		switch {
+		case trade.rMultiple > 0:
+			wins = append(wins, trade.rMultiple)
+		case trade.rMultiple < 0:
+			losses = append(losses, trade.rMultiple)
+		}
+	}
+	if decided := len(wins) + len(losses); decided > 0 {
+		distribution.WinRate = float64(len(wins)) / float64(decided)
+	}
+	distribution.AverageR = mean(rMultiples)
+	distribution.AverageWinR = mean(wins)
+	distribution.AverageLossR = mean(losses)
+	distribution.StdDev = sampleStdDev(rMultiples)
+	sort.Float64s(rMultiples)
+	distribution.MedianR = percentile(rMultiples, 0.5)
+
+	if distribution.Buckets, err = rBuckets(rMultiples, bucketSize); err != nil {
+		return distribution, err
+	}
+	return distribution, nil
+}
+
+// rBuckets counts sorted R-multiples into buckets of bucketSize R. every bucket from the lowest
+// R to the highest is there, empty ones included so the histogram has no gaps
+func rBuckets(rMultiples []float64, bucketSize float64) ([]RBucket, error) {
+	lowest := math.Floor(rMultiples[0] / bucketSize)
+	highest := math.Floor(rMultiples[len(rMultiples)-1] / bucketSize)
+	if highest-lowest >= maxRBuckets {
+		return nil, fmt.Errorf("%w: %v R wide buckets from %v to %v R would be more than %d", ErrTooManyBuckets,
+			bucketSize, rMultiples[0], rMultiples[len(rMultiples)-1], maxRBuckets)
+	}
+
+	first, last := int(lowest), int(highest)
+	buckets := make([]RBucket, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		buckets = append(buckets, RBucket{From: float64(i) * bucketSize, To: float64(i+1) * bucketSize})
+	}
+	for _, r := range rMultiples {
+		buckets[int(math.Floor(r/bucketSize))-first].Trades++
+	}
+	return buckets, nil
+}
+
+// GetRCurve adds up the R-multiples of the closed trades of a filter in the order they were closed
+func GetRCurve(db *sql.DB, userID int, filter StatsFilter) (RCurve, error) {
+	curve := RCurve{Points: []RCurvePoint{}}
+	trades, err := getRTrades(db, userID, filter)
+	if err != nil {
+		return curve, err
+	}
+
+	var peak float64
+	for _, trade := range trades {
+		curve.TotalR += trade.rMultiple
+		peak = math.Max(peak, curve.TotalR)
+		point := RCurvePoint{TradeID: trade.id, Time: trade.exitTime, R: trade.rMultiple, TotalR: curve.TotalR, DrawdownR: peak - curve.TotalR}
+		curve.MaxDrawdownR = math.Max(curve.MaxDrawdownR, point.DrawdownR)
+		curve.Points = append(curve.Points, point)
+	}
+	return curve, nil
+}
+
+// getRTrades returns every closed trade in scope that had a known risk, in the order they were
+// closed. R-multiples are ratios, so they don't need converting
+func getRTrades(db *sql.DB, userID int, filter StatsFilter) ([]rTrade, error) {
+	scope, args := filter.tradeWhere(userID)
+	rows, err := db.Query(`
+		SELECT t.id, t.exit_time, tm.r_multiple
+		FROM trades t
+		JOIN trade_metrics tm ON t.id = tm.trade_id
+		WHERE `+scope+` AND `+knownRiskRMultiple+` IS NOT NULL
+		ORDER BY t.exit_time, t.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve R-multiples: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []rTrade
+	for rows.Next() {
+		var trade rTrade
+		if err := rows.Scan(&trade.id, &trade.exitTime, &trade.rMultiple); err != nil {
+			return nil, fmt.Errorf("failed to scan R-multiple: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating R-multiples: %w", err)
+	}
+	return trades, nil
+}
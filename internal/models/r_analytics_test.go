@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRBuckets(t *testing.T) {
+	buckets, err := rBuckets([]float64{-1, -0.4, 0.2, 1.5, 1.9}, 0.5)
+	if err != nil {
+		t.Fatalf("rBuckets() error = %v", err)
+	}
+	want := []RBucket{
+		{From: -1, To: -0.5, Trades: 1},
+		{From: -0.5, To: 0, Trades: 1},
+		{From: 0, To: 0.5, Trades: 1},
+		{From: 0.5, To: 1, Trades: 0},
+		{From: 1, To: 1.5, Trades: 0},
+		{From: 1.5, To: 2, Trades: 2},
+	}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Errorf("rBuckets() = %+v, want %+v", buckets, want)
+	}
+
+	// one outlier with a tiny bucket size would need millions of buckets
+	if _, err := rBuckets([]float64{-1, 5000}, 0.001); !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("rBuckets() over a wide range error = %v, want ErrTooManyBuckets", err)
+	}
+	if _, err := rBuckets([]float64{0, 999.9}, 1); err != nil {
+		t.Errorf("rBuckets() with %d buckets error = %v, want none", maxRBuckets, err)
+	}
+}
+
+func TestRDistributionRejectsBucketSize(t *testing.T) {
+	// checked before the database is asked for anything
+	for _, size := range []float64{0, -0.5, math.NaN(), math.Inf(1)} {
+		if _, err := GetRDistribution(nil, 1, StatsFilter{}, size); !errors.Is(err, ErrInvalidBucketSize) {
+			t.Errorf("GetRDistribution() with bucket size %v error = %v, want ErrInvalidBucketSize", size, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"time"
+)
+
+// trading days in a year, used to annualize daily ratios
+const tradingDaysPerYear = 252
+
+// ExtendedStats are the risk adjusted measures. the ratios come from the daily returns of the
+// equity curve, so they're comparable between accounts of different sizes. days without a
+// closed trade, fee or adjustment aren't part of the series.
+// without a capital base (see EquityCurve) there are no returns, sharpe and sortino then come
+// from the daily P&L instead and the measures that need a return or a drawdown percent are null
+type ExtendedStats struct {
+	TradingDays       int      `json:"trading_days"`
+	AnnualizedReturn  *float64 `json:"annualized_return"` // percent
+	SharpeRatio       float64  `json:"sharpe_ratio"`      // annualized, no risk free rate
+	SortinoRatio      float64  `json:"sortino_ratio"`     // annualized, no risk free rate
+	CalmarRatio       *float64 `json:"calmar_ratio"`
+	UlcerIndex        *float64 `json:"ulcer_index"`
+	SQN               float64  `json:"sqn"`
+	KellyFraction     float64  `json:"kelly_fraction"`
+	RecoveryFactor    float64  `json:"recovery_factor"`
+	RMultipleStdDev   float64  `json:"r_multiple_std_dev"`
+	LongestWinStreak  int      `json:"longest_win_streak"`
+	LongestLossStreak int      `json:"longest_loss_streak"`
+}
+
+// dailyEquity is one day of the equity curve
+type dailyEquity struct {
+	date            time.Time
+	profitLoss      float64
+	growth          float64 // return of the day, 1.01 for +1%
+	drawdownPercent float64 // at the end of the day
+}
+
+// calculateExtendedStats works out the extended statistics from the equity curve, the basic
+// statistics and the R-multiples of the trades that had a known risk. the days are the user's,
+// in location
+func calculateExtendedStats(stats AggregateTradeStats, curve EquityCurve, rMultiples []float64, location *time.Location) ExtendedStats {
+	var extended ExtendedStats
+
+	days := dailyEquitySeries(curve, location)
+	extended.TradingDays = len(days)
+	// the curve only has a total return when every trade could be measured against the equity
+	hasCapital := curve.TotalReturn != nil
+	if len(days) > 0 {
+		returns := make([]float64, len(days))
+		var squaredDrawdowns float64
+		for i, day := range days {
+			if hasCapital {
+				returns[i] = day.growth - 1
+			} else {
+				returns[i] = day.profitLoss
+			}
+			squaredDrawdowns += day.drawdownPercent * day.drawdownPercent
+		}
+
+		avgReturn := mean(returns)
+		if sd := sampleStdDev(returns); sd > 0 {
+			extended.SharpeRatio = avgReturn / sd * math.Sqrt(tradingDaysPerYear)
+		}
+		// sortino only counts the days that lost money as risk
+		var downside float64
+		for _, r := range returns {
+			if r < 0 {
+				downside += r * r
+			}
+		}
+		if downside > 0 {
+			extended.SortinoRatio = avgReturn / math.Sqrt(downside/float64(len(returns))) * math.Sqrt(tradingDaysPerYear)
+		}
+
+		if hasCapital {
+			ulcer := math.Sqrt(squaredDrawdowns / float64(len(days)))
+			extended.UlcerIndex = &ulcer
+
+			// compound the total return over the calendar time the curve covers
+			start := curve.Points[0].Time
+			end := curve.Points[len(curve.Points)-1].Time
+			years := end.Sub(start).Hours() / 24 / 365.25
+			growth := 1 + *curve.TotalReturn/100
+			if years > 0 && growth > 0 {
+				annualized := (math.Pow(growth, 1/years) - 1) * 100
+				extended.AnnualizedReturn = &annualized
+				if *curve.MaxDrawdownPercent > 0 {
+					calmar := annualized / *curve.MaxDrawdownPercent
+					extended.CalmarRatio = &calmar
+				}
+			}
+		}
+	}
+
+	// van tharp's system quality number, with the number of trades capped at 100 so a big
+	// sample doesn't make a mediocre system look good
+	if len(rMultiples) > 1 {
+		extended.RMultipleStdDev = sampleStdDev(rMultiples)
+		if extended.RMultipleStdDev > 0 {
+			n := math.Min(float64(len(rMultiples)), 100)
+			extended.SQN = math.Sqrt(n) * mean(rMultiples) / extended.RMultipleStdDev
+		}
+	}
+
+	// kelly is the win rate minus the loss rate over the payoff ratio
+	if stats.AverageLoser < 0 {
+		payoff := stats.AverageWinner / -stats.AverageLoser
+		if payoff > 0 {
+			extended.KellyFraction = stats.WinRate - (1-stats.WinRate)/payoff
+		} else {
+			extended.KellyFraction = stats.WinRate - 1
+		}
+	} else if stats.WinningTrades > 0 {
+		extended.KellyFraction = stats.WinRate
+	}
+
+	if curve.MaxDrawdown > 0 {
+		extended.RecoveryFactor = stats.NetProfitLoss / curve.MaxDrawdown
+	}
+
+	// streaks run over the trades in the order they were closed, a break-even trade ends both
+	var wins, losses int
+	for _, point := range curve.Points {
+		if point.Kind != "trade" {
+			continue
+		}
+		switch {
+		case point.Amount > 0:
+			wins++
+			losses = 0
+		case point.Amount < 0:
+			losses++
+			wins = 0
+		default:
+			wins, losses = 0, 0
+		}
+		extended.LongestWinStreak = max(extended.LongestWinStreak, wins)
+		extended.LongestLossStreak = max(extended.LongestLossStreak, losses)
+	}
+
+	return extended
+}
+
+// dailyEquitySeries groups the performance on the equity curve by day in location, the points
+// are in UTC. each event's return is against the equity right before it, so deposits and
+// withdrawals don't count as returns
+func dailyEquitySeries(curve EquityCurve, location *time.Location) []dailyEquity {
+	var days []dailyEquity
+	for _, point := range curve.Points {
+		when := point.Time.In(location)
+		if point.Kind == "start" || point.Kind == "deposit" || point.Kind == "withdrawal" {
+			// cash flows still move the drawdown of a day that's already on the series
+			if n := len(days); n > 0 && sameDay(days[n-1].date, when) && point.DrawdownPercent != nil {
+				days[n-1].drawdownPercent = *point.DrawdownPercent
+			}
+			continue
+		}
+
+		if n := len(days); n == 0 || !sameDay(days[n-1].date, when) {
+			days = append(days, dailyEquity{date: when, growth: 1})
+		}
+		day := &days[len(days)-1]
+		day.profitLoss += point.Amount
+		if before := point.Equity - point.Amount; before > 0 {
+			day.growth *= 1 + point.Amount/before
+		}
+		if point.DrawdownPercent != nil {
+			day.drawdownPercent = *point.DrawdownPercent
+		}
+	}
+	return days
+}
+
+// getRMultiples returns the R-multiple of every closed trade in scope that had a known risk, in
+// the order they were closed
+func getRMultiples(db *sql.DB, userID int, filter StatsFilter) ([]float64, error) {
+	trades, err := getRTrades(db, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	rMultiples := make([]float64, len(trades))
+	for i, trade := range trades {
+		rMultiples[i] = trade.rMultiple
+	}
+	return rMultiples, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sampleStdDev is the standard deviation of a sample, so it divides by n-1
+func sampleStdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
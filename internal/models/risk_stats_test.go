@@ -0,0 +1,162 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// a hand-worked curve: a 10,000 account makes 10% on day one, gives back 10% over two losing
+// trades on day two and makes 10% on each of the last two days. the last trade closes exactly a
+// year after the account was opened, so the annualized return equals the total return
+//
+//	day   trades             equity  day return  drawdown
+//	1     +1000              11000   +10%        0%
+//	2     -550, -550          9900   -10%        10%
+//	3     0, +990            10890   +10%        1%
+//	4     +1089              11979   +10%        0%
+func testCurveEvents() (time.Time, []equityEvent) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time {
+		return start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+	}
+	trade := func(when time.Time, amount float64) equityEvent {
+		return equityEvent{time: when, kind: "trade", amount: amount}
+	}
+	return start, []equityEvent{
+		trade(at(1, 15), 1000),
+		trade(at(2, 14), -550),
+		trade(at(2, 15), -550),
+		trade(at(5, 14), 0),
+		trade(at(5, 15), 990),
+		trade(start.Add(time.Duration(365.25*24)*time.Hour), 1089),
+	}
+}
+
+// the basic statistics of the trades above, with the break-even trade left out of the win rate
+var testCurveStats = AggregateTradeStats{
+	TotalTrades:     6,
+	WinningTrades:   3,
+	LosingTrades:    2,
+	BreakEvenTrades: 1,
+	WinRate:         0.6,
+	AverageWinner:   (1000 + 990 + 1089) / 3.0,
+	AverageLoser:    -550,
+	NetProfitLoss:   1979,
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-6
+}
+
+func TestCalculateExtendedStats(t *testing.T) {
+	start, events := testCurveEvents()
+	curve := buildEquityCurve(10000, start, events)
+	rMultiples := []float64{2, -1, -1, 1.8, 2.2}
+
+	if curve.TotalReturn == nil || !closeTo(*curve.TotalReturn, 19.79) {
+		t.Fatalf("total return = %v, want 19.79", curve.TotalReturn)
+	}
+	if curve.MaxDrawdownPercent == nil || !closeTo(*curve.MaxDrawdownPercent, 10) || curve.MaxDrawdown != 1100 {
+		t.Fatalf("max drawdown = %v (%v%%), want 1100 (10%%)", curve.MaxDrawdown, curve.MaxDrawdownPercent)
+	}
+
+	extended := calculateExtendedStats(testCurveStats, curve, rMultiples, time.UTC)
+
+	if extended.TradingDays != 4 {
+		t.Errorf("TradingDays = %d, want 4", extended.TradingDays)
+	}
+	// daily returns .1, -.1, .1, .1: a mean of .05 over a sample std dev of .1, and a downside
+	// deviation of sqrt(.01/4) = .05
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"SharpeRatio", extended.SharpeRatio, 0.5 * math.Sqrt(252)},
+		{"SortinoRatio", extended.SortinoRatio, math.Sqrt(252)},
+		// sqrt((0² + 10² + 1² + 0²) / 4)
+		{"UlcerIndex", deref(extended.UlcerIndex), math.Sqrt(101.0 / 4)},
+		{"AnnualizedReturn", deref(extended.AnnualizedReturn), 19.79},
+		{"CalmarRatio", deref(extended.CalmarRatio), 1.979},
+		// mean .8, squared deviations add up to 10.88, so the std dev is sqrt(10.88/4)
+		{"RMultipleStdDev", extended.RMultipleStdDev, math.Sqrt(2.72)},
+		{"SQN", extended.SQN, math.Sqrt(5) * 0.8 / math.Sqrt(2.72)},
+		// a payoff ratio of 1026.33/550
+		{"KellyFraction", extended.KellyFraction, 0.6 - 0.4*550/(3079/3.0)},
+		{"RecoveryFactor", extended.RecoveryFactor, 1979.0 / 1100},
+	}
+	for _, c := range checks {
+		if !closeTo(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// win, loss, loss, break-even, win, win
+	if extended.LongestWinStreak != 2 || extended.LongestLossStreak != 2 {
+		t.Errorf("streaks = %d wins, %d losses, want 2 and 2", extended.LongestWinStreak, extended.LongestLossStreak)
+	}
+}
+
+func TestCalculateExtendedStatsWithoutCapital(t *testing.T) {
+	start, events := testCurveEvents()
+	curve := buildEquityCurve(0, start, events)
+
+	if curve.TotalReturn != nil || curve.MaxDrawdownPercent != nil {
+		t.Fatalf("curve without capital has total return %v and drawdown %v, want both null",
+			curve.TotalReturn, curve.MaxDrawdownPercent)
+	}
+	if curve.MaxDrawdown != 1100 {
+		t.Errorf("max drawdown = %v, want 1100", curve.MaxDrawdown)
+	}
+
+	extended := calculateExtendedStats(testCurveStats, curve, nil, time.UTC)
+
+	// daily P&L of 1000, -1100, 990 and 1089
+	pnl := []float64{1000, -1100, 990, 1089}
+	wantSharpe := mean(pnl) / sampleStdDev(pnl) * math.Sqrt(252)
+	wantSortino := mean(pnl) / math.Sqrt(1100*1100/4.0) * math.Sqrt(252)
+	if !closeTo(extended.SharpeRatio, wantSharpe) || !closeTo(wantSharpe, 7.380820311571389) {
+		t.Errorf("SharpeRatio = %v, want %v", extended.SharpeRatio, wantSharpe)
+	}
+	if !closeTo(extended.SortinoRatio, wantSortino) {
+		t.Errorf("SortinoRatio = %v, want %v", extended.SortinoRatio, wantSortino)
+	}
+	if extended.UlcerIndex != nil || extended.AnnualizedReturn != nil || extended.CalmarRatio != nil {
+		t.Errorf("ulcer %v, annualized return %v and calmar %v, want all null",
+			extended.UlcerIndex, extended.AnnualizedReturn, extended.CalmarRatio)
+	}
+	if !closeTo(extended.RecoveryFactor, 1979.0/1100) {
+		t.Errorf("RecoveryFactor = %v, want %v", extended.RecoveryFactor, 1979.0/1100)
+	}
+}
+
+func deref(f *float64) float64 {
+	if f == nil {
+		return math.NaN()
+	}
+	return *f
+}
+
+// an evening session in new york runs past midnight UTC, it's still one day of the user's
+func TestDailyEquitySeriesInUserTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	curve := buildEquityCurve(10000, start, []equityEvent{
+		{time: time.Date(2025, 6, 2, 14, 0, 0, 0, time.UTC), kind: "trade", amount: 100}, // 10am in new york
+		{time: time.Date(2025, 6, 3, 2, 0, 0, 0, time.UTC), kind: "trade", amount: -50},  // 10pm the same day
+	})
+
+	if days := dailyEquitySeries(curve, time.UTC); len(days) != 2 {
+		t.Errorf("%d UTC days, want 2", len(days))
+	}
+	days := dailyEquitySeries(curve, newYork)
+	if len(days) != 1 {
+		t.Fatalf("%d new york days, want 1", len(days))
+	}
+	if got := days[0].date.Format("2006-01-02"); got != "2025-06-02" || days[0].profitLoss != 50 {
+		t.Errorf("day %s made %v, want 2025-06-02 and 50", got, days[0].profitLoss)
+	}
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// one user must never be able to read or change another user's rows. every call below is made
+// by the intruder against the owner's rows and has to come back as ErrNotFound without
+// touching anything
+
+func TestTradeScoping(t *testing.T) {
+	db := openTestDB(t)
+	owner, ownerAccount := createTestUser(t, db)
+	intruder, intruderAccount := createTestUser(t, db)
+	trade := addTestTrade(t, db, owner, ownerAccount, 101, 100)
+	addTestTrade(t, db, intruder, intruderAccount, 99.5, 100)
+
+	if _, err := GetTrade(db, intruder, trade.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTrade() error = %v, want ErrNotFound", err)
+	}
+
+	changed := trade
+	changed.Ticker = "MSFT"
+	changed.EntryPrice = 1
+	changed.AccountID = intruderAccount
+	if err := UpdateTrade(db, intruder, changed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTrade() error = %v, want ErrNotFound", err)
+	}
+	// moving a trade into someone else's account doesn't work either
+	changed.AccountID = ownerAccount
+	if err := UpdateTrade(db, intruder, changed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTrade() into the owner's account error = %v, want ErrNotFound", err)
+	}
+	if _, err := AddTrade(db, Trade{UserID: intruder, AccountID: ownerAccount, Ticker: "AAPL", Direction: "LONG",
+		EntryPrice: 100, Quantity: 1, EntryTime: trade.EntryTime, TradeDate: trade.TradeDate}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddTrade() into the owner's account error = %v, want ErrNotFound", err)
+	}
+
+	if err := DeleteTrade(db, intruder, trade.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteTrade() error = %v, want ErrNotFound", err)
+	}
+
+	stored, err := GetTrade(db, owner, trade.ID)
+	if err != nil {
+		t.Fatalf("owner can't read their trade: %v", err)
+	}
+	if stored.Ticker != "AAPL" || stored.EntryPrice != 100 || stored.AccountID != ownerAccount {
+		t.Errorf("trade was changed: %s at %v in account %d", stored.Ticker, stored.EntryPrice, stored.AccountID)
+	}
+
+	for _, user := range []int{owner, intruder} {
+		trades, err := ListTrades(db, user, TradeFilter{})
+		if err != nil {
+			t.Fatalf("ListTrades() error = %v", err)
+		}
+		if len(trades) != 1 {
+			t.Errorf("user %d lists %d trades, want 1", user, len(trades))
+		}
+		for _, listed := range trades {
+			if listed.UserID != user {
+				t.Errorf("user %d listed trade %d of user %d", user, listed.ID, listed.UserID)
+			}
+		}
+	}
+}
+
+func TestTagScoping(t *testing.T) {
+	db := openTestDB(t)
+	owner, ownerAccount := createTestUser(t, db)
+	intruder, intruderAccount := createTestUser(t, db)
+	ownerTrade := addTestTrade(t, db, owner, ownerAccount, 101, 100)
+	intruderTrade := addTestTrade(t, db, intruder, intruderAccount, 99.5, 100)
+
+	tag := Tag{UserID: owner, Name: "breakout", Category: "setup", Color: "#00ff00"}
+	if err := CreateTag(db, &tag); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	intruderTag := Tag{UserID: intruder, Name: "breakout", Category: "setup", Color: "#ff0000"}
+	if err := CreateTag(db, &intruderTag); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if err := AddTagToTrade(db, owner, ownerTrade.ID, tag.ID); err != nil {
+		t.Fatalf("AddTagToTrade() error = %v", err)
+	}
+
+	if _, err := GetTag(db, intruder, tag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTag() error = %v, want ErrNotFound", err)
+	}
+	renamed := Tag{ID: tag.ID, UserID: intruder, Name: "renamed", Category: "setup", Color: "#000000"}
+	if err := UpdateTag(db, &renamed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTag() error = %v, want ErrNotFound", err)
+	}
+	if err := DeleteTag(db, tag.ID, intruder); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteTag() error = %v, want ErrNotFound", err)
+	}
+	stored, err := GetTag(db, owner, tag.ID)
+	if err != nil {
+		t.Fatalf("owner can't read their tag: %v", err)
+	}
+	if stored.Name != "breakout" || stored.Color != "#00ff00" {
+		t.Errorf("tag was changed: %+v", stored)
+	}
+
+	tags, err := GetTagsByUserID(db, intruder)
+	if err != nil {
+		t.Fatalf("GetTagsByUserID() error = %v", err)
+	}
+	if len(tags) != 1 || tags[0].ID != intruderTag.ID {
+		t.Errorf("intruder lists tags %+v, want only their own", tags)
+	}
+
+	// trade tags need both the trade and the tag to be the caller's
+	if err := AddTagToTrade(db, intruder, ownerTrade.ID, intruderTag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddTagToTrade() on the owner's trade error = %v, want ErrNotFound", err)
+	}
+	if err := AddTagToTrade(db, intruder, intruderTrade.ID, tag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddTagToTrade() with the owner's tag error = %v, want ErrNotFound", err)
+	}
+	if _, err := GetTagsByTradeID(db, intruder, ownerTrade.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTagsByTradeID() error = %v, want ErrNotFound", err)
+	}
+	if err := RemoveTagFromTrade(db, intruder, ownerTrade.ID, tag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveTagFromTrade() error = %v, want ErrNotFound", err)
+	}
+	ownerTags, err := GetTagsByTradeID(db, owner, ownerTrade.ID)
+	if err != nil {
+		t.Fatalf("GetTagsByTradeID() error = %v", err)
+	}
+	if len(ownerTags) != 1 || ownerTags[0].ID != tag.ID {
+		t.Errorf("owner's trade has tags %+v, want only %d", ownerTags, tag.ID)
+	}
+	intruderTags, err := GetTagsByTradeID(db, intruder, intruderTrade.ID)
+	if err != nil {
+		t.Fatalf("GetTagsByTradeID() error = %v", err)
+	}
+	if len(intruderTags) != 0 {
+		t.Errorf("intruder's trade has tags %+v, want none", intruderTags)
+	}
+}
+
+func TestExecutionScoping(t *testing.T) {
+	db := openTestDB(t)
+	owner, ownerAccount := createTestUser(t, db)
+	intruder, intruderAccount := createTestUser(t, db)
+	ownerTrade := addTestTrade(t, db, owner, ownerAccount, 101, 100)
+	intruderTrade := addTestTrade(t, db, intruder, intruderAccount, 99.5, 100)
+
+	execution := Execution{
+		UserID:     owner,
+		AccountID:  ownerAccount,
+		TradeID:    &ownerTrade.ID,
+		Ticker:     "AAPL",
+		Side:       "BUY",
+		Quantity:   100,
+		Price:      100,
+		ExecutedAt: time.Date(2025, 5, 1, 14, 30, 0, 0, time.UTC),
+		Fees:       1,
+	}
+	id, err := AddExecution(db, execution)
+	if err != nil {
+		t.Fatalf("AddExecution() error = %v", err)
+	}
+	execution.ID = id
+
+	// fills can't be added to someone else's account
+	misplaced := execution
+	misplaced.UserID = intruder
+	if _, err := AddExecution(db, misplaced); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddExecution() into the owner's account error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := GetExecutionsByTradeID(db, intruder, ownerTrade.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetExecutionsByTradeID() error = %v, want ErrNotFound", err)
+	}
+	moved := execution
+	moved.TradeID = &intruderTrade.ID
+	moved.Quantity = 1
+	if err := UpdateExecutionMatch(db, intruder, moved); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateExecutionMatch() error = %v, want ErrNotFound", err)
+	}
+	if err := DeleteExecution(db, execution.ID, intruder); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteExecution() error = %v, want ErrNotFound", err)
+	}
+
+	stored, err := GetExecutionsByTradeID(db, owner, ownerTrade.ID)
+	if err != nil {
+		t.Fatalf("GetExecutionsByTradeID() error = %v", err)
+	}
+	if len(stored) != 1 || stored[0].ID != execution.ID || stored[0].Quantity != 100 {
+		t.Errorf("owner's fills are %+v, want the one untouched fill", stored)
+	}
+
+	listed, err := ListExecutions(db, intruder)
+	if err != nil {
+		t.Fatalf("ListExecutions() error = %v", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("intruder lists %d executions, want none", len(listed))
+	}
+}
+
+func TestStatisticsScoping(t *testing.T) {
+	db := openTestDB(t)
+	owner, ownerAccount := createTestUser(t, db)
+	intruder, intruderAccount := createTestUser(t, db)
+	addTestTrade(t, db, owner, ownerAccount, 101, 100)
+	addTestTrade(t, db, intruder, intruderAccount, 99.5, 100)
+	addTestTrade(t, db, intruder, intruderAccount, 99, 100)
+
+	tests := []struct {
+		user   int
+		trades int
+		net    float64
+	}{
+		{owner, 1, 100},
+		{intruder, 2, -150},
+	}
+	for _, tt := range tests {
+		stats, err := GetBasicStats(db, tt.user, StatsFilter{})
+		if err != nil {
+			t.Fatalf("GetBasicStats() error = %v", err)
+		}
+		if stats.TotalTrades != tt.trades || stats.NetProfitLoss != tt.net {
+			t.Errorf("user %d: %d trades netting %v, want %d netting %v", tt.user, stats.TotalTrades,
+				stats.NetProfitLoss, tt.trades, tt.net)
+		}
+	}
+
+	// filtering on someone else's account finds nothing rather than their trades
+	filter := StatsFilter{TradeFilter: TradeFilter{AccountID: &intruderAccount}}
+	if _, err := GetBasicStats(db, owner, filter); !errors.Is(err, ErrNoTrades) {
+		t.Errorf("GetBasicStats() for the intruder's account error = %v, want ErrNoTrades", err)
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// CreateSession starts a new login session for a user and returns the token to give the client.
+// only a hash of the token is stored, so the sessions table can't be used to log in
+func CreateSession(db DbExecutor, userID int, ttl time.Duration) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	expiresAt := time.Now().Add(ttl).UTC()
+
+	_, err := db.Exec(`
+		INSERT INTO sessions (token_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, hashSessionToken(token), userID, expiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// GetSessionUserID returns the user a session token belongs to, as long as it hasn't expired
+func GetSessionUserID(db DbExecutor, token string) (int, error) {
+	var userID int
+	err := db.QueryRow(`
+		SELECT user_id FROM sessions WHERE token_hash = $1 AND expires_at > $2
+	`, hashSessionToken(token), time.Now().UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrSessionNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session: %w", err)
+	}
+	return userID, nil
+}
+
+func DeleteSession(db DbExecutor, token string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE token_hash = $1", hashSessionToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var ErrStrategyNameTaken = errors.New("a strategy with this name already exists")
+
+// Strategy is a setup of the user's playbook, with the rules for taking and managing it
+type Strategy struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	EntryRules  *string   `json:"entry_rules"`
+	ExitRules   *string   `json:"exit_rules"`
+	RiskRules   *string   `json:"risk_rules"`
+	Active      bool      `json:"active"` // retired strategies keep their trades and statistics
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StrategyStats is the performance of the closed trades taken on a strategy. trades without a
+// strategy are grouped with a nil StrategyID
+type StrategyStats struct {
+	StrategyID *int   `json:"strategy_id"`
+	Name       string `json:"name"`
+	Active     bool   `json:"active"`
+	PerformanceStats
+	Commissions          float64 `json:"commissions"`
+	AverageHoldingPeriod float64 `json:"average_holding_period"` // minutes
+}
+
+// StrategyBreakdown is the closed trades of a filter grouped by strategy
+type StrategyBreakdown struct {
+	Currency   string          `json:"currency"`
+	Strategies []StrategyStats `json:"strategies"`
+}
+
+func ValidateStrategy(strategy *Strategy) error {
+	strategy.Name = strings.TrimSpace(strategy.Name)
+	if strategy.Name == "" {
+		return errors.New("strategy name is required")
+	}
+	return nil
+}
+
+func CreateStrategy(db DbExecutor, strategy *Strategy) error {
+	err := db.QueryRow(`
+		INSERT INTO strategies (user_id, name, description, entry_rules, exit_rules, risk_rules, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, strategy.UserID, strategy.Name, strategy.Description, strategy.EntryRules, strategy.ExitRules,
+		strategy.RiskRules, strategy.Active).Scan(&strategy.ID, &strategy.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrStrategyNameTaken
+		}
+		log.Printf("Error creating strategy: %v", err)
+		return fmt.Errorf("failed to create strategy: %w", err)
+	}
+	return nil
+}
+
+const strategyColumns = "id, user_id, name, description, entry_rules, exit_rules, risk_rules, active, created_at"
+
+func scanStrategy(row interface{ Scan(...interface{}) error }) (Strategy, error) {
+	var s Strategy
+	err := row.Scan(&s.ID, &s.UserID, &s.Name, &s.Description, &s.EntryRules, &s.ExitRules, &s.RiskRules,
+		&s.Active, &s.CreatedAt)
+	return s, err
+}
+
+// get a strategy, as long as it belongs to the user
+func GetStrategy(db DbExecutor, userID, id int) (Strategy, error) {
+	strategy, err := scanStrategy(db.QueryRow("SELECT "+strategyColumns+" FROM strategies WHERE id = $1 AND user_id = $2", id, userID))
+	if err == sql.ErrNoRows {
+		return strategy, fmt.Errorf("strategy with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return strategy, fmt.Errorf("failed to get strategy: %w", err)
+	}
+	return strategy, nil
+}
+
+// ListStrategies returns the user's strategies by name, only the active ones if activeOnly is set
+func ListStrategies(db DbExecutor, userID int, activeOnly bool) ([]Strategy, error) {
+	query := "SELECT " + strategyColumns + " FROM strategies WHERE user_id = $1"
+	if activeOnly {
+		query += " AND active"
+	}
+	rows, err := db.Query(query+" ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve strategies: %w", err)
+	}
+	defer rows.Close()
+
+	strategies := []Strategy{}
+	for rows.Next() {
+		strategy, err := scanStrategy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan strategy: %w", err)
+		}
+		strategies = append(strategies, strategy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating strategies: %w", err)
+	}
+	return strategies, nil
+}
+
+func UpdateStrategy(db DbExecutor, strategy *Strategy) error {
+	err := db.QueryRow(`
+		UPDATE strategies
+		SET name = $1, description = $2, entry_rules = $3, exit_rules = $4, risk_rules = $5, active = $6
+		WHERE id = $7 AND user_id = $8
+		RETURNING created_at
+	`, strategy.Name, strategy.Description, strategy.EntryRules, strategy.ExitRules, strategy.RiskRules,
+		strategy.Active, strategy.ID, strategy.UserID).Scan(&strategy.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("strategy with ID %d %w", strategy.ID, ErrNotFound)
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrStrategyNameTaken
+		}
+		return fmt.Errorf("failed to update strategy: %w", err)
+	}
+	return nil
+}
+
+// delete a strategy, its trades are kept without one
+func DeleteStrategy(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM strategies WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete strategy: %w", err)
+	}
+	return expectAffected(result, "strategy", id)
+}
+
+// checkStrategyOwner returns ErrNotFound unless the strategy exists and belongs to the user
+func checkStrategyOwner(db DbExecutor, userID, strategyID int) error {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM strategies WHERE id = $1 AND user_id = $2)", strategyID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check strategy: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("strategy with ID %d %w", strategyID, ErrNotFound)
+	}
+	return nil
+}
+
+// GetStrategyBreakdown groups the closed trades of a filter by the strategy they were taken on,
+// best net P&L first
+func GetStrategyBreakdown(db *sql.DB, userID int, filter StatsFilter) (StrategyBreakdown, error) {
+	breakdown := StrategyBreakdown{Strategies: []StrategyStats{}}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Currency = scope.currency
+
+	// built before the query runs, commissions adds an arg
+	query := `
+		WITH grouped AS (
+			SELECT
+				s.id AS strategy_id,
+				COALESCE(s.name, '') AS name,
+				COALESCE(s.active, false) AS active,
+				tm.profit_loss,
+				` + knownRiskRMultiple + ` AS r_multiple,
+				tm.holding_period_minutes,
+				` + scope.commissions() + ` AS commissions
+			FROM trades t
+			JOIN ` + scope.metrics + ` tm ON t.id = tm.trade_id
+			LEFT JOIN strategies s ON s.id = t.strategy_id
+			WHERE ` + scope.where + `
+		)
+		SELECT
+			strategy_id, name, active, ` + performanceColumns + `,
+			COALESCE(SUM(commissions), 0),
+			COALESCE(AVG(holding_period_minutes), 0)
+		FROM grouped
+		GROUP BY strategy_id, name, active
+		ORDER BY COALESCE(SUM(profit_loss), 0) DESC, name
+	`
+	rows, err := db.Query(query, scope.args...)
+	if err != nil {
+		return breakdown, fmt.Errorf("failed to retrieve strategy breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s StrategyStats
+		dest := append([]interface{}{&s.StrategyID, &s.Name, &s.Active}, s.scanDest()...)
+		dest = append(dest, &s.Commissions, &s.AverageHoldingPeriod)
+		if err := rows.Scan(dest...); err != nil {
+			return breakdown, fmt.Errorf("failed to scan strategy statistics: %w", err)
+		}
+		s.finish()
+		breakdown.Strategies = append(breakdown.Strategies, s)
+	}
+	if err := rows.Err(); err != nil {
+		return breakdown, fmt.Errorf("error iterating strategy breakdown: %w", err)
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FuturesSymbol is a futures ticker split into its parts. continuous symbols like /ES
+// only have a root
+type FuturesSymbol struct {
+	Contract  string `json:"contract"`
+	Root      string `json:"root"`
+	MonthCode string `json:"month_code"`
+	Month     int    `json:"month"`
+	Year      int    `json:"year"`
+}
+
+// futures month codes, F is january through Z for december
+const futuresMonthCodes = "FGHJKMNQUVXZ"
+
+var (
+	// ESM5, ESM25, MESZ2024, /MESZ24. the root is matched lazily so roots ending in a
+	// letter that's also a month code (NQ, ZN, ZF) still split correctly
+	monthCodeSymbol = regexp.MustCompile(`^/?([A-Z0-9]{1,4}?)([FGHJKMNQUVXZ])(\d{1,2}|\d{4})$`)
+	// ES 06-25, ES 06-2025 (NinjaTrader)
+	numericMonthSymbol = regexp.MustCompile(`^([A-Z0-9]{1,4})\s+(\d{2})-(\d{2}|\d{4})$`)
+	// ES JUN25, ES JUN 2025
+	monthNameSymbol = regexp.MustCompile(`^/?([A-Z0-9]{1,4})\s+(JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)\s*(\d{2}|\d{4})$`)
+	// /ES, the continuous contract in thinkorswim
+	continuousSymbol = regexp.MustCompile(`^/([A-Z0-9]{1,4})$`)
+)
+
+var monthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// ParseFuturesSymbol splits a futures ticker in one of the common broker notations into root,
+// month and year. single digit years are resolved to the first matching year from ref on,
+// since nobody trades an expired contract. ok is false when the ticker doesn't look like a
+// futures symbol, which is the case for stocks
+func ParseFuturesSymbol(symbol string, ref time.Time) (FuturesSymbol, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	parsed := FuturesSymbol{Contract: symbol}
+
+	var month int
+	var year string
+	if m := monthCodeSymbol.FindStringSubmatch(symbol); m != nil {
+		parsed.Root = m[1]
+		month = strings.Index(futuresMonthCodes, m[2]) + 1
+		year = m[3]
+	} else if m := numericMonthSymbol.FindStringSubmatch(symbol); m != nil {
+		parsed.Root = m[1]
+		month, _ = strconv.Atoi(m[2])
+		year = m[3]
+	} else if m := monthNameSymbol.FindStringSubmatch(symbol); m != nil {
+		parsed.Root = m[1]
+		for i, name := range monthNames {
+			if name == m[2] {
+				month = i + 1
+			}
+		}
+		year = m[3]
+	} else if m := continuousSymbol.FindStringSubmatch(symbol); m != nil {
+		parsed.Root = m[1]
+		return parsed, true
+	} else {
+		return parsed, false
+	}
+
+	if month < 1 || month > 12 {
+		return FuturesSymbol{Contract: symbol}, false
+	}
+	parsed.Month = month
+	parsed.MonthCode = string(futuresMonthCodes[month-1])
+	parsed.Year = resolveContractYear(year, ref)
+	return parsed, true
+}
+
+// RootSymbol returns the continuous root of a ticker, so ESM5 and ESU5 both give ES.
+// anything that isn't a futures symbol is its own root
+func RootSymbol(ticker string, ref time.Time) string {
+	if parsed, ok := ParseFuturesSymbol(ticker, ref); ok {
+		return parsed.Root
+	}
+	return strings.ToUpper(strings.TrimSpace(ticker))
+}
+
+func resolveContractYear(year string, ref time.Time) int {
+	y, _ := strconv.Atoi(year)
+	switch len(year) {
+	case 4:
+		return y
+	case 2:
+		return 2000 + y
+	}
+
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	// one digit, pick the first year from ref's year on that ends in it
+	resolved := ref.Year() - ref.Year()%10 + y
+	if resolved < ref.Year() {
+		resolved += 10
+	}
+	return resolved
+}
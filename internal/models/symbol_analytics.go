@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrUnknownSymbolGroup = errors.New("unknown symbol grouping, use ticker or root_symbol")
+	ErrUnknownSortField   = errors.New("unknown sort field")
+)
+
+// SymbolStats is the performance of the closed trades of one ticker, root symbol or direction
+type SymbolStats struct {
+	Symbol string `json:"symbol"` // ESM5, ES, LONG or SHORT
+	PerformanceStats
+	GrossProfitLoss      float64 `json:"gross_profit_loss"` // before commissions
+	Commissions          float64 `json:"commissions"`
+	AverageHoldingPeriod float64 `json:"average_holding_period"` // minutes
+	BestTrade            float64 `json:"best_trade"`
+	WorstTrade           float64 `json:"worst_trade"`
+}
+
+// SymbolBreakdown is the closed trades of a filter grouped by symbol and by direction
+type SymbolBreakdown struct {
+	GroupBy    string        `json:"group_by"`
+	Currency   string        `json:"currency"`
+	Symbols    []SymbolStats `json:"symbols"`
+	Directions []SymbolStats `json:"directions"`
+}
+
+// the columns a breakdown can be grouped by
+var symbolGroupColumns = map[string]string{
+	"ticker":      "t.ticker",
+	"root_symbol": "t.root_symbol",
+}
+
+// the fields a breakdown can be sorted by, every one but symbol is a number
+var symbolSortFields = map[string]func(SymbolStats) float64{
+	"trades":                 func(s SymbolStats) float64 { return float64(s.Trades) },
+	"win_rate":               func(s SymbolStats) float64 { return s.WinRate },
+	"net_profit_loss":        func(s SymbolStats) float64 { return s.NetProfitLoss },
+	"gross_profit_loss":      func(s SymbolStats) float64 { return s.GrossProfitLoss },
+	"commissions":            func(s SymbolStats) float64 { return s.Commissions },
+	"expectancy":             func(s SymbolStats) float64 { return s.Expectancy },
+	"profit_factor":          func(s SymbolStats) float64 { return s.ProfitFactor },
+	"average_holding_period": func(s SymbolStats) float64 { return s.AverageHoldingPeriod },
+	"best_trade":             func(s SymbolStats) float64 { return s.BestTrade },
+	"worst_trade":            func(s SymbolStats) float64 { return s.WorstTrade },
+}
+
+// GetSymbolBreakdown groups the closed trades of a filter by ticker or root symbol and by
+// direction. the filter's SortBy and SortDesc order the groups, by net P&L from the best down
+// when it has none
+func GetSymbolBreakdown(db *sql.DB, userID int, filter StatsFilter, groupBy string) (SymbolBreakdown, error) {
+	if groupBy == "" {
+		groupBy = "ticker"
+	}
+	breakdown := SymbolBreakdown{GroupBy: groupBy, Symbols: []SymbolStats{}, Directions: []SymbolStats{}}
+
+	column, ok := symbolGroupColumns[groupBy]
+	if !ok {
+		return breakdown, fmt.Errorf("%w: %q", ErrUnknownSymbolGroup, groupBy)
+	}
+	sortBy, desc := filter.SortBy, filter.SortDesc
+	if sortBy == "" {
+		sortBy, desc = "net_profit_loss", true
+	}
+	if _, ok := symbolSortFields[sortBy]; !ok && sortBy != "symbol" {
+		return breakdown, fmt.Errorf("%w %q", ErrUnknownSortField, sortBy)
+	}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Currency = scope.currency
+
+	if breakdown.Symbols, err = symbolStats(db, scope, column); err != nil {
+		return breakdown, err
+	}
+	if breakdown.Directions, err = symbolStats(db, scope, "t.direction"); err != nil {
+		return breakdown, err
+	}
+	sortSymbolStats(breakdown.Symbols, sortBy, desc)
+	sortSymbolStats(breakdown.Directions, sortBy, desc)
+	return breakdown, nil
+}
+
+// symbolStats groups the trades in scope by a column of the trades table
+func symbolStats(db *sql.DB, scope convertedScope, column string) ([]SymbolStats, error) {
+	// built before the query runs, commissions adds an arg
+	query := `
+		WITH grouped AS (
+			SELECT
+				` + column + ` AS symbol,
+				tm.profit_loss,
+				` + knownRiskRMultiple + ` AS r_multiple,
+				tm.holding_period_minutes,
+				` + scope.commissions() + ` AS commissions
+			FROM trades t
+			JOIN ` + scope.metrics + ` tm ON t.id = tm.trade_id
+			WHERE ` + scope.where + `
+		)
+		SELECT
+			symbol, ` + performanceColumns + `,
+			COALESCE(SUM(commissions), 0),
+			COALESCE(AVG(holding_period_minutes), 0),
+			MAX(profit_loss),
+			MIN(profit_loss)
+		FROM grouped
+		GROUP BY symbol
+	`
+	rows, err := db.Query(query, scope.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve symbol breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []SymbolStats{}
+	for rows.Next() {
+		var s SymbolStats
+		dest := append([]interface{}{&s.Symbol}, s.scanDest()...)
+		dest = append(dest, &s.Commissions, &s.AverageHoldingPeriod, &s.BestTrade, &s.WorstTrade)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol statistics: %w", err)
+		}
+		s.finish()
+		s.GrossProfitLoss = s.NetProfitLoss + s.Commissions
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating symbol breakdown: %w", err)
+	}
+	return stats, nil
+}
+
+// sortSymbolStats orders the groups by a field, ties go by symbol
+func sortSymbolStats(stats []SymbolStats, sortBy string, desc bool) {
+	value := symbolSortFields[sortBy]
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if value != nil && value(a) != value(b) {
+			return (value(a) < value(b)) != desc
+		}
+		if value == nil && desc {
+			return a.Symbol > b.Symbol
+		}
+		return a.Symbol < b.Symbol
+	})
+}
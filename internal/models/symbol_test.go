@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFuturesSymbol(t *testing.T) {
+	june2025 := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		symbol string
+		ref    time.Time
+		want   FuturesSymbol
+		ok     bool
+	}{
+		{"ESM5", june2025, FuturesSymbol{Contract: "ESM5", Root: "ES", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{"ESM25", june2025, FuturesSymbol{Contract: "ESM25", Root: "ES", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{"ESM2025", june2025, FuturesSymbol{Contract: "ESM2025", Root: "ES", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{" esu5 ", june2025, FuturesSymbol{Contract: "ESU5", Root: "ES", MonthCode: "U", Month: 9, Year: 2025}, true},
+		// roots with a digit, and roots ending in a letter that's also a month code
+		{"6EM5", june2025, FuturesSymbol{Contract: "6EM5", Root: "6E", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{"MESZ4", june2025, FuturesSymbol{Contract: "MESZ4", Root: "MES", MonthCode: "Z", Month: 12, Year: 2034}, true},
+		{"NQZ5", june2025, FuturesSymbol{Contract: "NQZ5", Root: "NQ", MonthCode: "Z", Month: 12, Year: 2025}, true},
+		{"ZNU5", june2025, FuturesSymbol{Contract: "ZNU5", Root: "ZN", MonthCode: "U", Month: 9, Year: 2025}, true},
+		{"/MESZ24", june2025, FuturesSymbol{Contract: "/MESZ24", Root: "MES", MonthCode: "Z", Month: 12, Year: 2024}, true},
+		// one digit years are the first one from the reference date on, across the decade too
+		{"MESZ4", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), FuturesSymbol{Contract: "MESZ4", Root: "MES", MonthCode: "Z", Month: 12, Year: 2024}, true},
+		{"ESH0", time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC), FuturesSymbol{Contract: "ESH0", Root: "ES", MonthCode: "H", Month: 3, Year: 2030}, true},
+		// NinjaTrader and month name notations
+		{"ES 06-25", june2025, FuturesSymbol{Contract: "ES 06-25", Root: "ES", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{"MNQ 09-2025", june2025, FuturesSymbol{Contract: "MNQ 09-2025", Root: "MNQ", MonthCode: "U", Month: 9, Year: 2025}, true},
+		{"ES JUN25", june2025, FuturesSymbol{Contract: "ES JUN25", Root: "ES", MonthCode: "M", Month: 6, Year: 2025}, true},
+		{"CL DEC 2025", june2025, FuturesSymbol{Contract: "CL DEC 2025", Root: "CL", MonthCode: "Z", Month: 12, Year: 2025}, true},
+		{"ES 13-25", june2025, FuturesSymbol{Contract: "ES 13-25"}, false},
+		// continuous contracts only have a root
+		{"/ES", june2025, FuturesSymbol{Contract: "/ES", Root: "ES"}, true},
+		// stocks aren't futures
+		{"AAPL", june2025, FuturesSymbol{Contract: "AAPL"}, false},
+		{"ES", june2025, FuturesSymbol{Contract: "ES"}, false},
+		{"BRK.B", june2025, FuturesSymbol{Contract: "BRK.B"}, false},
+		{"", june2025, FuturesSymbol{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseFuturesSymbol(tt.symbol, tt.ref)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseFuturesSymbol(%q, %s) = %+v, %v, want %+v, %v", tt.symbol, tt.ref.Format("2006-01"), got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRootSymbol(t *testing.T) {
+	june2025 := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ticker, want string
+	}{
+		{"ESM5", "ES"},
+		{"ESU25", "ES"},
+		{"6EM5", "6E"},
+		{"MESZ4", "MES"},
+		{"ES 06-25", "ES"},
+		{"/ES", "ES"},
+		{" aapl ", "AAPL"},
+		{"SPY", "SPY"},
+	}
+	for _, tt := range tests {
+		if got := RootSymbol(tt.ticker, june2025); got != tt.want {
+			t.Errorf("RootSymbol(%q) = %q, want %q", tt.ticker, got, tt.want)
+		}
+	}
+}
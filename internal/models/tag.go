@@ -55,30 +55,70 @@ func GetTagsByUserID(db *sql.DB, userID int) ([]Tag, error) {
 	return tags, nil
 }
 
-// add a tag to a trade
-func AddTagToTrade(db *sql.DB, tradeID, tagID int) error {
-	_, err := db.Exec("INSERT INTO trade_tags (trade_id, tag_id) VALUES ($1, $2)", tradeID, tagID)
+// get a single tag, as long as it belongs to the user
+func GetTag(db *sql.DB, userID, tagID int) (Tag, error) {
+	var t Tag
+	err := db.QueryRow("SELECT id, user_id, name, category, color FROM tags WHERE id = $1 AND user_id = $2", tagID, userID).
+		Scan(&t.ID, &t.UserID, &t.Name, &t.Category, &t.Color)
+	if err == sql.ErrNoRows {
+		return t, fmt.Errorf("tag with ID %d %w", tagID, ErrNotFound)
+	}
+	if err != nil {
+		return t, fmt.Errorf("error retrieving tag: %w", err)
+	}
+	return t, nil
+}
+
+// add a tag to a trade. both the trade and the tag have to belong to the user
+func AddTagToTrade(db *sql.DB, userID, tradeID, tagID int) error {
+	result, err := db.Exec(`
+		INSERT INTO trade_tags (trade_id, tag_id)
+		SELECT tr.id, tg.id
+		FROM trades tr, tags tg
+		WHERE tr.id = $1 AND tr.user_id = $3 AND tg.id = $2 AND tg.user_id = $3
+	`, tradeID, tagID, userID)
+	if err != nil {
+		return fmt.Errorf("error adding tag to trade: %w", err)
+	}
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("error adding tag to trade: %w", err)
 	}
+	if affected == 0 {
+		return fmt.Errorf("trade %d or tag %d %w", tradeID, tagID, ErrNotFound)
+	}
 	return nil
 }
 
-func RemoveTagFromTrade(db *sql.DB, tradeID, tagID int) error {
-	_, err := db.Exec("DELETE FROM trade_tags WHERE trade_id = $1 AND tag_id = $2", tradeID, tagID)
+func RemoveTagFromTrade(db *sql.DB, userID, tradeID, tagID int) error {
+	result, err := db.Exec(`
+		DELETE FROM trade_tags tt
+		USING trades tr
+		WHERE tt.trade_id = tr.id AND tt.trade_id = $1 AND tt.tag_id = $2 AND tr.user_id = $3
+	`, tradeID, tagID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting tag from trade: %w", err)
+	}
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("error deleting tag from trade: %w", err)
 	}
+	if affected == 0 {
+		return fmt.Errorf("tag %d on trade %d %w", tagID, tradeID, ErrNotFound)
+	}
 	return nil
 }
 
 // get all tags for a trade
-func GetTagsByTradeID(db *sql.DB, tradeID int) ([]Tag, error) {
+func GetTagsByTradeID(db *sql.DB, userID, tradeID int) ([]Tag, error) {
+	if err := checkTradeOwner(db, userID, tradeID); err != nil {
+		return nil, err
+	}
 	// takes trade id and joins with tags and trade_tags to get the tags for the trade
 	rows, err := db.Query(`SELECT t.id, t.user_id, t.name, t.category, t.color
 							FROM tags t 
 							JOIN trade_tags tt ON t.id = tt.tag_id 
-							WHERE tt.trade_id = $1`, tradeID)
+							WHERE tt.trade_id = $1 AND t.user_id = $2`, tradeID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting tags: %w", err)
 	}
@@ -99,17 +139,17 @@ func GetTagsByTradeID(db *sql.DB, tradeID int) ([]Tag, error) {
 }
 
 func UpdateTag(db *sql.DB, tag *Tag) error {
-	_, err := db.Exec("UPDATE tags SET name = $1, category = $2, color = $3 WHERE id = $4 AND user_id = $5", tag.Name, tag.Category, tag.Color, tag.ID, tag.UserID)
+	result, err := db.Exec("UPDATE tags SET name = $1, category = $2, color = $3 WHERE id = $4 AND user_id = $5", tag.Name, tag.Category, tag.Color, tag.ID, tag.UserID)
 	if err != nil {
 		return fmt.Errorf("error updating tag: %w", err)
 	}
-	return nil
+	return expectAffected(result, "tag", tag.ID)
 }
 
 func DeleteTag(db *sql.DB, tagID, userID int) error {
-	_, err := db.Exec("DELETE FROM tags WHERE id = $1 AND user_id = $2", tagID, userID)
+	result, err := db.Exec("DELETE FROM tags WHERE id = $1 AND user_id = $2", tagID, userID)
 	if err != nil {
 		return fmt.Errorf("error deleting tag: %w", err)
 	}
-	return nil
+	return expectAffected(result, "tag", tagID)
 }
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// TagStats is the performance of the closed trades with one tag
+type TagStats struct {
+	TagID    int    `json:"tag_id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	PerformanceStats
+}
+
+// TagCategoryStats is the performance of the closed trades with any tag of a category. a trade
+// with two tags of the same category counts once
+type TagCategoryStats struct {
+	Category string `json:"category"` // empty for tags without a category
+	PerformanceStats
+}
+
+// TagBreakdown is the closed trades of a filter grouped by tag and by tag category. the
+// selection is every trade of the filter, tagged or not, so ?tag_id=1,2&exclude_tag_id=3 gives
+// the performance of the trades tagged both 1 and 2 but not 3
+type TagBreakdown struct {
+	Currency   string             `json:"currency"`
+	Selection  PerformanceStats   `json:"selection"`
+	Tags       []TagStats         `json:"tags"`
+	Categories []TagCategoryStats `json:"categories"`
+}
+
+// GetTagBreakdown groups the closed trades of a filter by tag and by tag category
+func GetTagBreakdown(db *sql.DB, userID int, filter StatsFilter) (TagBreakdown, error) {
+	breakdown := TagBreakdown{Tags: []TagStats{}, Categories: []TagCategoryStats{}}
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Currency = scope.currency
+
+	filtered := `
+		WITH filtered AS (
+			SELECT t.id, tm.profit_loss, ` + knownRiskRMultiple + ` AS r_multiple
+			FROM trades t
+			JOIN ` + scope.metrics + ` tm ON t.id = tm.trade_id
+			WHERE ` + scope.where + `
+		)`
+
+	err = db.QueryRow(filtered+`
+		SELECT `+performanceColumns+`
+		FROM filtered
+	`, scope.args...).Scan(breakdown.Selection.scanDest()...)
+	if err != nil {
+		return breakdown, fmt.Errorf("failed to retrieve selection statistics: %w", err)
+	}
+	breakdown.Selection.finish()
+
+	rows, err := db.Query(filtered+`
+		SELECT tg.id, tg.name, COALESCE(tg.category, ''), `+performanceColumns+`
+		FROM filtered
+		JOIN trade_tags tt ON tt.trade_id = filtered.id
+		JOIN tags tg ON tg.id = tt.tag_id
+		GROUP BY tg.id, tg.name, tg.category
+		ORDER BY COALESCE(SUM(profit_loss), 0) DESC, tg.name
+	`, scope.args...)
+	if err != nil {
+		return breakdown, fmt.Errorf("failed to retrieve tag breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s TagStats
+		if err := rows.Scan(append([]interface{}{&s.TagID, &s.Name, &s.Category}, s.scanDest()...)...); err != nil {
+			return breakdown, fmt.Errorf("failed to scan tag statistics: %w", err)
+		}
+		s.finish()
+		breakdown.Tags = append(breakdown.Tags, s)
+	}
+	if err := rows.Err(); err != nil {
+		return breakdown, fmt.Errorf("error iterating tag breakdown: %w", err)
+	}
+
+	// one row per trade and category, so the trade isn't counted again for every tag it has
+	// in the same category
+	categoryRows, err := db.Query(filtered+`
+		SELECT category, `+performanceColumns+`
+		FROM (
+			SELECT DISTINCT filtered.id, filtered.profit_loss, filtered.r_multiple, COALESCE(tg.category, '') AS category
+			FROM filtered
+			JOIN trade_tags tt ON tt.trade_id = filtered.id
+			JOIN tags tg ON tg.id = tt.tag_id
+		) categorized
+		GROUP BY category
+		ORDER BY category
+	`, scope.args...)
+	if err != nil {
+		return breakdown, fmt.Errorf("failed to retrieve tag category breakdown: %w", err)
+	}
+	defer categoryRows.Close()
+
+	for categoryRows.Next() {
+		var s TagCategoryStats
+		if err := categoryRows.Scan(append([]interface{}{&s.Category}, s.scanDest()...)...); err != nil {
+			return breakdown, fmt.Errorf("failed to scan tag category statistics: %w", err)
+		}
+		s.finish()
+		breakdown.Categories = append(breakdown.Categories, s)
+	}
+	if err := categoryRows.Err(); err != nil {
+		return breakdown, fmt.Errorf("error iterating tag category breakdown: %w", err)
+	}
+	return breakdown, nil
+}
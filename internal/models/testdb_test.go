@@ -0,0 +1,107 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/lib/pq"
+)
+
+// tests that need postgres run against a throwaway database in TEST_DATABASE_URL, e.g.
+// TEST_DATABASE_URL=postgres://localhost/journal_test?sslmode=disable go test ./...
+// they're skipped without one
+
+var testUserCount int64
+
+// openTestDB connects to the test database and brings it up to the latest migration
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	m, err := migrate.New("file://../db/schema", url)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	m.Close()
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createTestUser adds a user with a default account, both are removed again with everything
+// they own when the test is done
+func createTestUser(t *testing.T, db *sql.DB) (userID, accountID int) {
+	t.Helper()
+	email := fmt.Sprintf("test-%d-%d@example.com", time.Now().UnixNano(), atomic.AddInt64(&testUserCount, 1))
+	user, err := CreateUser(db, email, "correct horse")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { deleteTestUser(t, db, user.ID) })
+
+	accountID, err = GetOrCreateAccountByName(db, user.ID, DefaultAccountName, nil)
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	return user.ID, accountID
+}
+
+func deleteTestUser(t *testing.T, db *sql.DB, userID int) {
+	// trades take their metrics, tags and legs with them, the user its accounts, sessions and ledger
+	for _, table := range []string{"executions", "trades", "tags", "users"} {
+		column := "user_id"
+		if table == "users" {
+			column = "id"
+		}
+		if _, err := db.Exec("DELETE FROM "+table+" WHERE "+column+" = $1", userID); err != nil {
+			t.Errorf("failed to clean up %s of user %d: %v", table, userID, err)
+		}
+	}
+}
+
+// addTestTrade adds a closed stock trade with its metrics, bought at 100 and sold at exitPrice
+func addTestTrade(t *testing.T, db *sql.DB, userID, accountID int, exitPrice, quantity float64) Trade {
+	t.Helper()
+	entryTime := time.Date(2025, 5, 1, 14, 30, 0, 0, time.UTC)
+	exitTime := entryTime.Add(time.Hour)
+	trade := Trade{
+		UserID:     userID,
+		AccountID:  accountID,
+		Ticker:     "AAPL",
+		Direction:  "LONG",
+		Status:     TradeStatusClosed,
+		EntryPrice: 100,
+		ExitPrice:  &exitPrice,
+		Quantity:   quantity,
+		TradeDate:  entryTime,
+		EntryTime:  entryTime,
+		ExitTime:   &exitTime,
+	}
+	id, err := AddTrade(db, trade)
+	if err != nil {
+		t.Fatalf("failed to add trade: %v", err)
+	}
+	trade.ID = id
+	if err := CalculateAndInsertTradeMetrics(db, trade); err != nil {
+		t.Fatalf("failed to add trade metrics: %v", err)
+	}
+	return trade
+}
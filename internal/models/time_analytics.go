@@ -0,0 +1,134 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownTimeBucket = errors.New("unknown time bucket")
+
+// the buckets trades can be grouped by. day, week and month go by the exit, since that's when
+// the P&L is realized. hour, weekday and session go by the entry, since that's when the
+// decision to trade was made
+const (
+	TimeBucketDay     = "day"
+	TimeBucketWeek    = "week"
+	TimeBucketMonth   = "month"
+	TimeBucketHour    = "hour"
+	TimeBucketWeekday = "weekday"
+	TimeBucketSession = "session"
+)
+
+// TimeBucketStats is the performance of the trades in one bucket
+type TimeBucketStats struct {
+	Bucket string `json:"bucket"` // 2025-06-02, 2025-W23, 2025-06, 09, Monday or new_york
+	PerformanceStats
+}
+
+// TimeBreakdown is the closed trades of a filter grouped into time buckets
+type TimeBreakdown struct {
+	BucketType string            `json:"bucket_type"`
+	Timezone   string            `json:"timezone"`
+	Currency   string            `json:"currency"`
+	Buckets    []TimeBucketStats `json:"buckets"`
+}
+
+// timeBucketSQL returns the expression for a bucket's key and one to order the buckets by.
+// local is the placeholder of the user's timezone
+func timeBucketSQL(bucket, local string) (key string, order string, err error) {
+	// timestamps are stored in UTC
+	localExit := fmt.Sprintf("((t.exit_time AT TIME ZONE 'UTC') AT TIME ZONE %s)", local)
+	localEntry := fmt.Sprintf("((t.entry_time AT TIME ZONE 'UTC') AT TIME ZONE %s)", local)
+	// sessions are fixed in New York time whatever the user's timezone is. the globex day
+	// starts with asia at 18:00 the evening before
+	newYork := "((t.entry_time AT TIME ZONE 'UTC') AT TIME ZONE 'America/New_York')::time"
+
+	switch bucket {
+	case TimeBucketDay:
+		key = "to_char(" + localExit + ", 'YYYY-MM-DD')"
+		return key, key, nil
+	case TimeBucketWeek:
+		key = "to_char(" + localExit + `, 'IYYY-"W"IW')`
+		return key, key, nil
+	case TimeBucketMonth:
+		key = "to_char(" + localExit + ", 'YYYY-MM')"
+		return key, key, nil
+	case TimeBucketHour:
+		key = "to_char(" + localEntry + ", 'HH24')"
+		return key, key, nil
+	case TimeBucketWeekday:
+		return "to_char(" + localEntry + ", 'FMDay')", "to_char(" + localEntry + ", 'ID')", nil
+	case TimeBucketSession:
+		key = fmt.Sprintf(`CASE
+			WHEN %[1]s >= '03:00' AND %[1]s < '09:30' THEN 'london'
+			WHEN %[1]s >= '09:30' AND %[1]s < '16:00' THEN 'new_york'
+			WHEN %[1]s >= '16:00' AND %[1]s < '18:00' THEN 'after_hours'
+			ELSE 'asia'
+		END`, newYork)
+		order = fmt.Sprintf(`CASE
+			WHEN %[1]s >= '03:00' AND %[1]s < '09:30' THEN 2
+			WHEN %[1]s >= '09:30' AND %[1]s < '16:00' THEN 3
+			WHEN %[1]s >= '16:00' AND %[1]s < '18:00' THEN 4
+			ELSE 1
+		END`, newYork)
+		return key, order, nil
+	}
+	return "", "", fmt.Errorf("%w %q, use day, week, month, hour, weekday or session", ErrUnknownTimeBucket, bucket)
+}
+
+// GetTimeBreakdown groups the closed trades of a filter by a time bucket, in the user's timezone
+func GetTimeBreakdown(db *sql.DB, userID int, filter StatsFilter, bucket string) (TimeBreakdown, error) {
+	breakdown := TimeBreakdown{BucketType: bucket, Buckets: []TimeBucketStats{}}
+
+	timezone, err := userTimezone(db, userID)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Timezone = timezone
+
+	scope, err := newConvertedScope(db, userID, filter)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Currency = scope.currency
+
+	key, order, err := timeBucketSQL(bucket, scope.param(timezone))
+	if err != nil {
+		return breakdown, err
+	}
+
+	rows, err := db.Query(`
+		WITH bucketed AS (
+			SELECT
+				`+key+` AS bucket,
+				`+order+` AS bucket_order,
+				tm.profit_loss,
+				`+knownRiskRMultiple+` AS r_multiple
+			FROM trades t
+			JOIN `+scope.metrics+` tm ON t.id = tm.trade_id
+			WHERE `+scope.where+`
+		)
+		SELECT bucket, `+performanceColumns+`
+		FROM bucketed
+		GROUP BY bucket
+		ORDER BY MIN(bucket_order)
+	`, scope.args...)
+	if err != nil {
+		return breakdown, fmt.Errorf("failed to retrieve %s breakdown: %w", bucket, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b TimeBucketStats
+		if err := rows.Scan(append([]interface{}{&b.Bucket}, b.scanDest()...)...); err != nil {
+			return breakdown, fmt.Errorf("failed to scan %s bucket: %w", bucket, err)
+		}
+		b.finish()
+		breakdown.Buckets = append(breakdown.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return breakdown, fmt.Errorf("error iterating %s breakdown: %w", bucket, err)
+	}
+	return breakdown, nil
+}
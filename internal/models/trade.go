@@ -12,37 +12,191 @@ import (
 )
 
 type Trade struct {
-	ID            int       `json:"id"`
-	UserID        int       `json:"user_id"`
-	Ticker        string    `json:"ticker"`
-	Direction     string    `json:"direction"`
-	EntryPrice    float64   `json:"entry_price"`
-	ExitPrice     float64   `json:"exit_price"`
-	Quantity      float64   `json:"quantity"`
-	TradeDate     time.Time `json:"trade_date"`
-	EntryTime     time.Time `json:"entry_time"`
-	ExitTime      time.Time `json:"exit_time"`
-	StopLoss      *float64  `json:"stop_loss"`
-	TakeProfit    *float64  `json:"take_profit"`
-	Commissions   *float64  `json:"commissions"`
-	HighestPrice  *float64  `json:"highest_price"`
-	LowestPrice   *float64  `json:"lowest_price"`
-	Notes         *string   `json:"notes"`
-	ScreenshotURL *string   `json:"screenshot_url"`
+	ID            int        `json:"id"`
+	UserID        int        `json:"user_id"`
+	AccountID     int        `json:"account_id"`
+	Ticker        string     `json:"ticker"`
+	RootSymbol    string     `json:"root_symbol"`
+	Direction     string     `json:"direction"`
+	Status        string     `json:"status"` // OPEN or CLOSED, open positions have no exit
+	EntryPrice    float64    `json:"entry_price"`
+	ExitPrice     *float64   `json:"exit_price"`
+	Quantity      float64    `json:"quantity"`
+	TradeDate     time.Time  `json:"trade_date"`
+	EntryTime     time.Time  `json:"entry_time"`
+	ExitTime      *time.Time `json:"exit_time"`
+	StopLoss      *float64   `json:"stop_loss"`
+	TakeProfit    *float64   `json:"take_profit"`
+	Commissions   *float64   `json:"commissions"`
+	HighestPrice  *float64   `json:"highest_price"`
+	LowestPrice   *float64   `json:"lowest_price"`
+	Notes         *string    `json:"notes"`
+	ScreenshotURL *string    `json:"screenshot_url"`
+	Source        *string    `json:"source"`
+	BrokerAccount *string    `json:"broker_account"`
+	Fingerprint   *string    `json:"fingerprint"`
+	Currency      string     `json:"currency"` // what the P&L is in, converted for statistics
+	// the planned risk, in money or in ticks, the R-multiple is worked out from it
+	PlannedRisk      *float64 `json:"planned_risk"`
+	PlannedRiskTicks *float64 `json:"planned_risk_ticks"`
+	// options trades have their contracts as legs, the trade holds the net premium
+	Strategy *string     `json:"strategy"`
+	Legs     []OptionLeg `json:"legs,omitempty"`
+	// the playbook strategy the trade was taken on, not the options structure above
+	StrategyID *int `json:"strategy_id"`
 }
 
 type TradeFilter struct {
-	StartDate *time.Time `json:"start_date"`
-	EndDate   *time.Time `json:"end_date"`
-	Ticker    string     `json:"ticker"`
-	MinProfit *float64   `json:"min_profit"`
-	MaxProfit *float64   `json:"max_profit"`
-	Limit     int        `json:"limit"`
-	Offset    int        `json:"offset"`
-	SortBy    string     `json:"sort_by"`
-	SortDesc  bool       `json:"sort_desc"`
+	StartDate     *time.Time `json:"start_date"`
+	EndDate       *time.Time `json:"end_date"`
+	ClosedFrom    *time.Time `json:"closed_from"` // exit time window, for going by the day trades were closed
+	ClosedBefore  *time.Time `json:"closed_before"`
+	Ticker        string     `json:"ticker"`
+	Direction     string     `json:"direction"`
+	RootSymbol    string     `json:"root_symbol"` // matches every contract month, e.g. ES for ESM5 and ESU5
+	AccountID     *int       `json:"account_id"`
+	StrategyID    *int       `json:"strategy_id"`
+	Status        string     `json:"status"`
+	TagIDs        []int      `json:"tag_ids"`         // trades that have every one of these tags
+	ExcludeTagIDs []int      `json:"exclude_tag_ids"` // and none of these
+	MinProfit     *float64   `json:"min_profit"`      // net P&L from the trade metrics
+	MaxProfit     *float64   `json:"max_profit"`
+	Limit         int        `json:"limit"`
+	Offset        int        `json:"offset"`
+	SortBy        string     `json:"sort_by"`
+	SortDesc      bool       `json:"sort_desc"`
 }
 
+// the fields the trade list can be sorted by and the columns they sort on
+var tradeSortColumns = map[string]string{
+	"id":          "t.id",
+	"ticker":      "t.ticker",
+	"root_symbol": "t.root_symbol",
+	"direction":   "t.direction",
+	"status":      "t.status",
+	"account_id":  "t.account_id",
+	"entry_price": "t.entry_price",
+	"exit_price":  "t.exit_price",
+	"quantity":    "t.quantity",
+	"commissions": "t.commissions",
+	"trade_date":  "t.trade_date",
+	"entry_time":  "t.entry_time",
+	"exit_time":   "t.exit_time",
+	"profit_loss": "(SELECT tm.profit_loss FROM trade_metrics tm WHERE tm.trade_id = t.id)",
+}
+
+const (
+	TradeStatusOpen   = "OPEN"
+	TradeStatusClosed = "CLOSED"
+)
+
+var ErrTradeClosed = errors.New("trade is already closed")
+
+// ValidatePlannedRisk checks the planned risk is positive and only given one way
+func ValidatePlannedRisk(trade Trade) error {
+	if trade.PlannedRisk != nil && trade.PlannedRiskTicks != nil {
+		return errors.New("set planned_risk or planned_risk_ticks, not both")
+	}
+	if (trade.PlannedRisk != nil && *trade.PlannedRisk <= 0) || (trade.PlannedRiskTicks != nil && *trade.PlannedRiskTicks <= 0) {
+		return errors.New("planned risk must be greater than 0")
+	}
+	return nil
+}
+
+// ValidateTradeStatus makes sure the status and the exit fields agree. without a status a
+// trade is open until it has an exit
+func ValidateTradeStatus(trade *Trade) error {
+	trade.Status = strings.ToUpper(strings.TrimSpace(trade.Status))
+	if trade.Status == "" {
+		trade.Status = TradeStatusClosed
+		if trade.ExitPrice == nil && trade.ExitTime == nil {
+			trade.Status = TradeStatusOpen
+		}
+	}
+
+	switch trade.Status {
+	case TradeStatusOpen:
+		if trade.ExitPrice != nil || trade.ExitTime != nil {
+			return errors.New("an open trade can't have an exit price or exit time")
+		}
+		// legs are P&L'd from their exit prices, so options are journaled once they're closed
+		if len(trade.Legs) > 0 {
+			return errors.New("option trades can only be added once they're closed")
+		}
+	case TradeStatusClosed:
+		if trade.ExitPrice == nil || trade.ExitTime == nil {
+			return errors.New("a closed trade needs an exit price and exit time")
+		}
+		if trade.ExitTime.Before(trade.EntryTime) {
+			return errors.New("exit time is before entry time")
+		}
+	default:
+		return errors.New("status must be OPEN or CLOSED")
+	}
+	return nil
+}
+
+// conditions turns the filter into where conditions on the trades table aliased as alias. the
+// filter's values are appended to args, so the conditions can be added to a query that already
+// has parameters. the trade list and the statistics both filter through here
+func (f TradeFilter) conditions(alias string, args []interface{}) ([]string, []interface{}) {
+	var conditions []string
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, alias, len(args)))
+	}
+
+	if f.StartDate != nil {
+		add("%s.trade_date >= $%d", f.StartDate.UTC())
+	}
+	if f.EndDate != nil {
+		add("%s.trade_date <= $%d", f.EndDate.UTC())
+	}
+	// exit times are stored in UTC
+	if f.ClosedFrom != nil {
+		add("%s.exit_time >= $%d", f.ClosedFrom.UTC())
+	}
+	if f.ClosedBefore != nil {
+		add("%s.exit_time < $%d", f.ClosedBefore.UTC())
+	}
+	if f.Ticker != "" {
+		add("%s.ticker = $%d", f.Ticker)
+	}
+	if f.Direction != "" {
+		add("%s.direction = $%d", strings.ToUpper(f.Direction))
+	}
+	if f.RootSymbol != "" {
+		add("%s.root_symbol = $%d", strings.ToUpper(f.RootSymbol))
+	}
+	if f.AccountID != nil {
+		add("%s.account_id = $%d", *f.AccountID)
+	}
+	if f.StrategyID != nil {
+		add("%s.strategy_id = $%d", *f.StrategyID)
+	}
+	if f.Status != "" {
+		add("%s.status = $%d", strings.ToUpper(f.Status))
+	}
+	for _, tagID := range f.TagIDs {
+		add("EXISTS (SELECT 1 FROM trade_tags ft WHERE ft.trade_id = %s.id AND ft.tag_id = $%d)", tagID)
+	}
+	for _, tagID := range f.ExcludeTagIDs {
+		add("NOT EXISTS (SELECT 1 FROM trade_tags ft WHERE ft.trade_id = %s.id AND ft.tag_id = $%d)", tagID)
+	}
+	// open positions have no metrics, so they never match a profit filter
+	if f.MinProfit != nil {
+		add("(SELECT fm.profit_loss FROM trade_metrics fm WHERE fm.trade_id = %s.id) >= $%d", *f.MinProfit)
+	}
+	if f.MaxProfit != nil {
+		add("(SELECT fm.profit_loss FROM trade_metrics fm WHERE fm.trade_id = %s.id) <= $%d", *f.MaxProfit)
+	}
+	return conditions, args
+}
+
+// ErrNotFound is returned when a row doesn't exist or belongs to another user,
+// so callers can't tell the two apart
+var ErrNotFound = errors.New("not found")
+
 type DbExecutor interface {
 	Prepare(query string) (*sql.Stmt, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
@@ -50,25 +204,53 @@ type DbExecutor interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
-type FuturesContract struct {
-	TickValue   float64 // Dollar value of one tick
-	TickSize float64 // Minimum price increment
+// normalizeTradeTimes converts the trade's times to UTC. the columns have no time zone, so a
+// time with another offset would be stored as its wall clock and read back as if it were UTC
+func normalizeTradeTimes(trade *Trade) {
+	trade.TradeDate = trade.TradeDate.UTC()
+	trade.EntryTime = trade.EntryTime.UTC()
+	if trade.ExitTime != nil {
+		exitTime := trade.ExitTime.UTC()
+		trade.ExitTime = &exitTime
+	}
 }
 
-var FuturesContractMap = map[string]FuturesContract{
-	"ES":  {12.50, 0.25},     // E-mini S&P 500 - $12.50 per point, 0.25 point minimum tick
-	"GC":  {10.0, 0.1},       // Gold futures - $10 per tick, 0.1 tick minimum tick
-}
+func AddTrade(db DbExecutor, trade Trade) (int, error) {
+	// the account has to belong to the same user as the trade
+	if err := checkAccountOwner(db, trade.UserID, trade.AccountID); err != nil {
+		return 0, err
+	}
+	// and so does its strategy
+	if trade.StrategyID != nil {
+		if err := checkStrategyOwner(db, trade.UserID, *trade.StrategyID); err != nil {
+			return 0, err
+		}
+	}
 
+	if err := ValidatePlannedRisk(trade); err != nil {
+		return 0, err
+	}
+	if err := ValidateTradeStatus(&trade); err != nil {
+		return 0, err
+	}
+	normalizeTradeTimes(&trade)
+	trade.RootSymbol = RootSymbol(trade.Ticker, trade.EntryTime)
+	currency, err := tradeCurrency(db, trade)
+	if err != nil {
+		return 0, err
+	}
+	trade.Currency = currency
 
-func AddTrade(db DbExecutor, trade Trade) (int, error) {
 	// prepare the SQL statement to insert the trade
 	stmt, err := db.Prepare(`
         INSERT INTO trades (
             ticker, direction, entry_price, exit_price, quantity, 
             trade_date, entry_time, exit_time, stop_loss, take_profit, 
-            commissions, highest_price, lowest_price, notes, screenshot_url, user_id
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+            commissions, highest_price, lowest_price, notes, screenshot_url, user_id,
+            source, broker_account, fingerprint, account_id, root_symbol, strategy, currency, status,
+            planned_risk, planned_risk_ticks, strategy_id
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+            $25, $26, $27)
         RETURNING id
     `)
 	if err != nil {
@@ -81,7 +263,8 @@ func AddTrade(db DbExecutor, trade Trade) (int, error) {
 		trade.TradeDate, trade.EntryTime, trade.ExitTime,
 		trade.StopLoss, trade.TakeProfit, trade.Commissions,
 		trade.HighestPrice, trade.LowestPrice, trade.Notes, trade.ScreenshotURL,
-		1,
+		trade.UserID, trade.Source, trade.BrokerAccount, trade.Fingerprint, trade.AccountID, trade.RootSymbol,
+		trade.Strategy, trade.Currency, trade.Status, trade.PlannedRisk, trade.PlannedRiskTicks, trade.StrategyID,
 	)
 	// scan the returned id
 	var id int
@@ -91,59 +274,99 @@ func AddTrade(db DbExecutor, trade Trade) (int, error) {
 		return 0, fmt.Errorf("failed to insert trade: %w", err)
 	}
 
+	if len(trade.Legs) > 0 {
+		if err := AddOptionLegs(db, id, trade.Legs); err != nil {
+			return 0, err
+		}
+	}
+
 	return id, nil
 }
 
-func CalculateAndInsertTradeMetrics(db *sql.DB, trade Trade) error {
+func CalculateAndInsertTradeMetrics(db DbExecutor, trade Trade) error {
 	if trade.ID == 0 {
 		return errors.New("trade ID is required")
 	}
+	if err := checkTradeOwner(db, trade.UserID, trade.ID); err != nil {
+		return err
+	}
+
+	// open positions only have unrealized P&L, which isn't stored. a trade that was reopened
+	// loses the metrics it had when it was closed
+	if trade.ExitPrice == nil || trade.ExitTime == nil {
+		if _, err := db.Exec("DELETE FROM trade_metrics WHERE trade_id = $1", trade.ID); err != nil {
+			return fmt.Errorf("failed to delete trade metrics: %w", err)
+		}
+		return nil
+	}
+	exitPrice := *trade.ExitPrice
 
 	// make sure direction is uppercase for case-insensitive comparison
 	direction := strings.ToUpper(trade.Direction)
+	if direction != "LONG" && direction != "SHORT" {
+		return fmt.Errorf("invalid trade direction: %s", trade.Direction)
+	}
+
+	// options trades are made of legs, load them if the caller didn't pass them in
+	legs := trade.Legs
+	if legs == nil {
+		byTrade, err := getOptionLegs(db, []int{trade.ID})
+		if err != nil {
+			return err
+		}
+		legs = byTrade[trade.ID]
+	}
+
+	// check if this is a known futures contract, dated contracts are looked up by their root
+	var instrument Instrument
+	var isFutures bool
+	if len(legs) == 0 {
+		var err error
+		instrument, isFutures, err = FindInstrument(db, trade.UserID, RootSymbol(trade.Ticker, trade.EntryTime))
+		if err != nil {
+			return err
+		}
+	}
 
 	// calculate profit/loss
 	var profitLoss float64
-	
-	// check if this is a known futures contract
-	if contract, isFutures := FuturesContractMap[trade.Ticker]; isFutures {
-		tickValue := contract.TickValue
-		tickSize := contract.TickSize
-		// calculate price difference
-		var priceDiff float64
-		switch direction {
-		case "LONG":
-			priceDiff = trade.ExitPrice - trade.EntryPrice
-		case "SHORT":
-			priceDiff = trade.EntryPrice - trade.ExitPrice
-		default:
-			return fmt.Errorf("invalid trade direction: %s", trade.Direction)
+	// a price move is worth the point value for futures, the multiplier for options,
+	// and just the price for everything else
+	pointValue := 1.0
+	// best and worst case at expiry, only known for options
+	var maxProfit, maxLoss *float64
+	if len(legs) > 0 {
+		// the trade's P&L is the sum of its legs
+		for i := range legs {
+			legs[i].ProfitLoss = legs[i].LegProfitLoss()
+			profitLoss += legs[i].ProfitLoss
+		}
+		if err := updateOptionLegProfitLoss(db, legs); err != nil {
+			return err
 		}
-		
-		// calculate number of ticks (rounding to the nearest valid tick)
-		numTicks := math.Round(priceDiff / tickSize)
-		
-		// calculate profit loss based on number of ticks and tick value
-		profitLoss = numTicks * tickValue * trade.Quantity
+		pointValue = legs[0].Multiplier
+		maxProfit, maxLoss = OptionPayoffRange(legs)
 	} else {
-		// for non-futures contracts, just use price difference * quantity
-		switch direction {
-		case "LONG":
-			profitLoss = (trade.ExitPrice - trade.EntryPrice) * trade.Quantity
-		case "SHORT":
-			profitLoss = (trade.EntryPrice - trade.ExitPrice) * trade.Quantity
-		default:
-			return fmt.Errorf("invalid trade direction: %s", trade.Direction)
+		profitLoss = priceProfitLoss(direction, trade.EntryPrice, exitPrice, trade.Quantity, instrument, isFutures)
+		if isFutures {
+			pointValue = instrument.PointValue
 		}
 	}
 
-	// deduct commissions (if provided)
-	if trade.Commissions != nil {
+	// deduct commissions (if provided). the legs of an options trade already paid their own
+	if trade.Commissions != nil && len(legs) == 0 {
 		profitLoss -= *trade.Commissions
 	}
 
 	// calculate profit/loss in a percentage
 	investment := trade.EntryPrice * trade.Quantity
+	if len(legs) > 0 {
+		// for options it's the capital at risk, the premium paid or the margin of a credit spread
+		investment *= pointValue
+		if maxLoss != nil && *maxLoss > 0 {
+			investment = *maxLoss
+		}
+	}
 	var profitLossPercent float64
 	if investment != 0 {
 		profitLossPercent = (profitLoss / investment) * 100
@@ -152,10 +375,14 @@ func CalculateAndInsertTradeMetrics(db *sql.DB, trade Trade) error {
 	// calculate holding period in minutes
 	holdingPeriod := int(trade.ExitTime.Sub(trade.EntryTime).Minutes())
 
-	// calculate risk-to-reward ratio and R-multiple
+	// calculate risk-to-reward ratio
 	var riskRewardRatio float64
-	var rMultiple float64
-	if trade.StopLoss != nil && *trade.StopLoss > 0 {
+	if maxLoss != nil && *maxLoss > 0 {
+		// defined risk options strategies can make at most their max profit
+		if maxProfit != nil {
+			riskRewardRatio = *maxProfit / *maxLoss
+		}
+	} else if trade.StopLoss != nil && *trade.StopLoss > 0 {
 		// calculate risk by subtracting the stop loss from the entry price
 		var risk float64
 		switch direction {
@@ -169,41 +396,48 @@ func CalculateAndInsertTradeMetrics(db *sql.DB, trade Trade) error {
 		var reward float64
 		switch direction {
 		case "LONG":
-			reward = trade.ExitPrice - trade.EntryPrice
+			reward = exitPrice - trade.EntryPrice
 		case "SHORT":
-			reward = trade.EntryPrice - trade.ExitPrice
+			reward = trade.EntryPrice - exitPrice
 		}
 
-		// if risk is greater than 0, calculate the risk-to-reward ratio and R-multiple
 		if risk > 0 {
 			riskRewardRatio = math.Abs(reward / risk)
-			rMultiple = reward / risk
 		}
 	}
 
+	// the R-multiple is the net P&L over the money the trade put at risk. without a known risk
+	// there's no R, and the metrics keep a nil risk amount
+	var rMultiple float64
+	riskAmount := tradeRiskAmount(trade, direction, legs, maxLoss, instrument, isFutures, pointValue)
+	if riskAmount != nil {
+		rMultiple = profitLoss / *riskAmount
+	}
+
 	// calculate MFE (Maximum Favorable Excursion)
 	// how far the trade went in the positive direction (depends on long or short trade)
 	var mfe float64
 	if direction == "LONG" && trade.HighestPrice != nil {
-		mfe = (*trade.HighestPrice - trade.EntryPrice) * trade.Quantity
+		mfe = (*trade.HighestPrice - trade.EntryPrice) * trade.Quantity * pointValue
 	} else if direction == "SHORT" && trade.LowestPrice != nil {
-		mfe = (trade.EntryPrice - *trade.LowestPrice) * trade.Quantity
+		mfe = (trade.EntryPrice - *trade.LowestPrice) * trade.Quantity * pointValue
 	}
 
 	// calculate MAE (Maximum Adverse Excursion)
 	// how far the trade went in the negative direction (depends on long or short trade)
 	var mae float64
 	if direction == "LONG" && trade.LowestPrice != nil {
-		mae = (trade.EntryPrice - *trade.LowestPrice) * trade.Quantity
+		mae = (trade.EntryPrice - *trade.LowestPrice) * trade.Quantity * pointValue
 	} else if direction == "SHORT" && trade.HighestPrice != nil {
-		mae = (*trade.HighestPrice - trade.EntryPrice) * trade.Quantity
+		mae = (*trade.HighestPrice - trade.EntryPrice) * trade.Quantity * pointValue
 	}
 
 	// insert or update metrics we calculated into the trade_metrics table
 	_, err := db.Exec(`
         INSERT INTO trade_metrics 
-        (trade_id, profit_loss, profit_loss_percent, risk_reward_ratio, r_multiple, holding_period_minutes, mfe, mae)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        (trade_id, profit_loss, profit_loss_percent, risk_reward_ratio, r_multiple, holding_period_minutes, mfe, mae,
+        max_profit, max_loss, risk_amount)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         ON CONFLICT (trade_id)  -- if there is an existing row with the same trade_id, update the row
         DO UPDATE SET 
 			-- use EXCLUDED to the values that are being updated
@@ -213,8 +447,12 @@ func CalculateAndInsertTradeMetrics(db *sql.DB, trade Trade) error {
             r_multiple = EXCLUDED.r_multiple,
             holding_period_minutes = EXCLUDED.holding_period_minutes,
             mfe = EXCLUDED.mfe,
-            mae = EXCLUDED.mae
-    `, trade.ID, profitLoss, profitLossPercent, riskRewardRatio, rMultiple, holdingPeriod, mfe, mae)
+            mae = EXCLUDED.mae,
+            max_profit = EXCLUDED.max_profit,
+            max_loss = EXCLUDED.max_loss,
+            risk_amount = EXCLUDED.risk_amount
+    `, trade.ID, profitLoss, profitLossPercent, riskRewardRatio, rMultiple, holdingPeriod, mfe, mae, maxProfit, maxLoss,
+		riskAmount)
 
 	if err != nil {
 		log.Printf("Error inserting/updating trade metrics: %v", err)
@@ -223,77 +461,121 @@ func CalculateAndInsertTradeMetrics(db *sql.DB, trade Trade) error {
 	return nil
 }
 
-// get a trade by id
-func GetTrade(db DbExecutor, id int) (Trade, error) {
+// tradeRiskAmount is the money a closed trade stood to lose, or nil when it isn't known. a planned
+// risk in money wins, then a planned risk in ticks, the max loss of a defined risk options
+// strategy and last the distance to the stop. the risks worked out from prices include the
+// commissions, since a trade stopped out pays them too
+func tradeRiskAmount(trade Trade, direction string, legs []OptionLeg, maxLoss *float64, instrument Instrument, isFutures bool, pointValue float64) *float64 {
+	var commissions float64
+	if trade.Commissions != nil {
+		commissions = *trade.Commissions
+	}
+
+	var risk float64
+	switch {
+	case trade.PlannedRisk != nil:
+		risk = *trade.PlannedRisk
+	case trade.PlannedRiskTicks != nil:
+		// anything that isn't futures moves in cents
+		tickValue := 0.01 * pointValue
+		if isFutures {
+			tickValue = instrument.TickValue
+		}
+		risk = *trade.PlannedRiskTicks*tickValue*trade.Quantity + commissions
+	case maxLoss != nil && *maxLoss > 0:
+		risk = *maxLoss
+	case trade.StopLoss != nil && *trade.StopLoss > 0:
+		// what going from the entry to the stop would have lost, nothing if the stop is on the wrong side
+		stopRisk := -priceProfitLoss(direction, trade.EntryPrice, *trade.StopLoss, trade.Quantity, instrument, isFutures)
+		if len(legs) > 0 {
+			stopRisk *= pointValue
+		}
+		if stopRisk > 0 {
+			risk = stopRisk + commissions
+		}
+	}
+	if risk <= 0 {
+		return nil
+	}
+	return &risk
+}
+
+// priceProfitLoss is what moving from the entry price to price is worth, before commissions.
+// futures are counted in whole ticks, everything else is the price difference times the quantity
+func priceProfitLoss(direction string, entryPrice, price, quantity float64, instrument Instrument, isFutures bool) float64 {
+	priceDiff := price - entryPrice
+	if strings.ToUpper(direction) == "SHORT" {
+		priceDiff = -priceDiff
+	}
+	if isFutures {
+		return instrument.PriceDiffValue(priceDiff, quantity)
+	}
+	return priceDiff * quantity
+}
+
+// get a trade by id, as long as it belongs to the user
+func GetTrade(db DbExecutor, userID, id int) (Trade, error) {
 	var trade Trade
 	stmt, err := db.Prepare(`
 		SELECT 
 			id, user_id, ticker, direction, entry_price, exit_price, quantity, trade_date, entry_time, 
-			exit_time, stop_loss, take_profit, commissions, highest_price, lowest_price, notes, screenshot_url
-		FROM trades WHERE id = $1
+			exit_time, stop_loss, take_profit, commissions, highest_price, lowest_price, notes, screenshot_url,
+			source, broker_account, fingerprint, account_id, root_symbol, strategy, currency, status,
+			planned_risk, planned_risk_ticks, strategy_id
+		FROM trades WHERE id = $1 AND user_id = $2
 	`)
 	if err != nil {
 		return trade, fmt.Errorf("failed to prepare query: %w", err)
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(id)
+	row := stmt.QueryRow(id, userID)
 	err = row.Scan(
 		&trade.ID, &trade.UserID, &trade.Ticker, &trade.Direction, &trade.EntryPrice, &trade.ExitPrice,
 		&trade.Quantity, &trade.TradeDate, &trade.EntryTime, &trade.ExitTime, &trade.StopLoss, &trade.TakeProfit,
 		&trade.Commissions, &trade.HighestPrice, &trade.LowestPrice, &trade.Notes, &trade.ScreenshotURL,
+		&trade.Source, &trade.BrokerAccount, &trade.Fingerprint, &trade.AccountID, &trade.RootSymbol, &trade.Strategy,
+		&trade.Currency, &trade.Status, &trade.PlannedRisk, &trade.PlannedRiskTicks, &trade.StrategyID,
 	)
 	if err == sql.ErrNoRows {
-		return trade, fmt.Errorf("trade with ID %d not found", id)
+		return trade, fmt.Errorf("trade with ID %d %w", id, ErrNotFound)
 	}
 	if err != nil {
 		return trade, fmt.Errorf("failed to scan trade: %w", err)
 	}
 
+	legs, err := getOptionLegs(db, []int{trade.ID})
+	if err != nil {
+		return trade, err
+	}
+	trade.Legs = legs[trade.ID]
+
 	return trade, nil
 }
 
-func ListTrades(db DbExecutor, filter TradeFilter) ([]Trade, error) {
-	var conditions []string
-	var parameters []interface{}
-	parameterIndex := 1
+func ListTrades(db DbExecutor, userID int, filter TradeFilter) ([]Trade, error) {
+	// only ever return the user's own trades
+	conditions := []string{"t.user_id = $1"}
+	parameters := []interface{}{userID}
 
 	// apply filters to query
-	if filter.StartDate != nil {
-		conditions = append(conditions, "trade_date >= $"+strconv.Itoa(parameterIndex))
-		parameters = append(parameters, *filter.StartDate)
-		parameterIndex++
-	}
-	if filter.EndDate != nil {
-		conditions = append(conditions, "trade_date <= $"+strconv.Itoa(parameterIndex))
-		parameters = append(parameters, *filter.EndDate)
-		parameterIndex++
-	}
-	if filter.Ticker != "" {
-		conditions = append(conditions, "ticker = $"+strconv.Itoa(parameterIndex))
-		parameters = append(parameters, filter.Ticker)
-		parameterIndex++
-	}
-	if filter.MinProfit != nil {
-		conditions = append(conditions, "(exit_price - entry_price) * quantity >= $"+strconv.Itoa(parameterIndex))
-		parameters = append(parameters, *filter.MinProfit)
-		parameterIndex++
-	}
-	if filter.MaxProfit != nil {
-		conditions = append(conditions, "(exit_price - entry_price) * quantity <= $"+strconv.Itoa(parameterIndex))
-		parameters = append(parameters, *filter.MaxProfit)
-		parameterIndex++
-	}
+	filterConditions, parameters := filter.conditions("t", parameters)
+	conditions = append(conditions, filterConditions...)
+	parameterIndex := len(parameters) + 1
 
 	// construct the base query
-	query := "SELECT id, user_id, ticker, direction, entry_price, exit_price, quantity, trade_date, entry_time, exit_time, stop_loss, take_profit, commissions, highest_price, lowest_price, notes, screenshot_url FROM trades"
+	query := "SELECT id, user_id, ticker, direction, entry_price, exit_price, quantity, trade_date, entry_time, exit_time, stop_loss, take_profit, commissions, highest_price, lowest_price, notes, screenshot_url, source, broker_account, fingerprint, account_id, root_symbol, strategy, currency, status, planned_risk, planned_risk_ticks, strategy_id FROM trades t"
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	// apply sorting
 	if filter.SortBy != "" {
-		query += " ORDER BY " + filter.SortBy
+		column, ok := tradeSortColumns[filter.SortBy]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownSortField, filter.SortBy)
+		}
+		query += " ORDER BY " + column
 		if filter.SortDesc {
 			query += " DESC"
 		} else {
@@ -328,7 +610,9 @@ func ListTrades(db DbExecutor, filter TradeFilter) ([]Trade, error) {
 			&trade.ID, &trade.UserID, &trade.Ticker, &trade.Direction, &trade.EntryPrice, &trade.ExitPrice,
 			&trade.Quantity, &trade.TradeDate, &trade.EntryTime, &trade.ExitTime, &trade.StopLoss,
 			&trade.TakeProfit, &trade.Commissions, &trade.HighestPrice, &trade.LowestPrice,
-			&trade.Notes, &trade.ScreenshotURL,
+			&trade.Notes, &trade.ScreenshotURL, &trade.Source, &trade.BrokerAccount, &trade.Fingerprint,
+			&trade.AccountID, &trade.RootSymbol, &trade.Strategy, &trade.Currency, &trade.Status,
+			&trade.PlannedRisk, &trade.PlannedRiskTicks, &trade.StrategyID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan trade row: %w", err)
@@ -340,23 +624,72 @@ func ListTrades(db DbExecutor, filter TradeFilter) ([]Trade, error) {
 		return nil, fmt.Errorf("error during row iteration: %w", err)
 	}
 
+	// fetch the option legs of the whole page in one go
+	tradeIDs := make([]int, len(trades))
+	for i, trade := range trades {
+		tradeIDs[i] = trade.ID
+	}
+	legs, err := getOptionLegs(db, tradeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trades {
+		trades[i].Legs = legs[trades[i].ID]
+	}
+
 	return trades, nil
 }
 
-func DeleteTrade(db DbExecutor, id int) error {
-	stmt, err := db.Prepare("DELETE FROM trades WHERE id = $1")
+// FindTradeIDByFingerprint looks up an imported trade by its fingerprint.
+// found is false when no trade has that fingerprint
+func FindTradeIDByFingerprint(db DbExecutor, userID int, fingerprint string) (id int, found bool, err error) {
+	err = db.QueryRow("SELECT id FROM trades WHERE user_id = $1 AND fingerprint = $2", userID, fingerprint).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to look up trade fingerprint: %w", err)
+	}
+	return id, true, nil
+}
+
+func DeleteTrade(db DbExecutor, userID, id int) error {
+	stmt, err := db.Prepare("DELETE FROM trades WHERE id = $1 AND user_id = $2")
 	if err != nil {
 		return fmt.Errorf("failed to delete trade: %w", err)
 	}
 	defer stmt.Close()
-	_, err = stmt.Exec(id)
+	result, err := stmt.Exec(id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete trade: %w", err)
 	}
-	return nil
+	return expectAffected(result, "trade", id)
 }
 
-func UpdateTrade(db DbExecutor, trade Trade) error {
+// update a trade, only if it belongs to the user
+func UpdateTrade(db DbExecutor, userID int, trade Trade) error {
+	if err := checkAccountOwner(db, userID, trade.AccountID); err != nil {
+		return err
+	}
+	if trade.StrategyID != nil {
+		if err := checkStrategyOwner(db, userID, *trade.StrategyID); err != nil {
+			return err
+		}
+	}
+	if err := ValidatePlannedRisk(trade); err != nil {
+		return err
+	}
+	if err := ValidateTradeStatus(&trade); err != nil {
+		return err
+	}
+	normalizeTradeTimes(&trade)
+	trade.RootSymbol = RootSymbol(trade.Ticker, trade.EntryTime)
+	currency, err := tradeCurrency(db, trade)
+	if err != nil {
+		return err
+	}
+	trade.Currency = currency
+
 	stmt, err := db.Prepare(`
 		UPDATE trades 
 		SET 
@@ -374,23 +707,90 @@ func UpdateTrade(db DbExecutor, trade Trade) error {
 			highest_price = $12, 
 			lowest_price = $13, 
 			notes = $14, 
-			screenshot_url = $15 
-		WHERE id = $16
+			screenshot_url = $15,
+			account_id = $18,
+			root_symbol = $19,
+			strategy = $20,
+			currency = $21,
+			status = $22,
+			planned_risk = $23,
+			planned_risk_ticks = $24,
+			strategy_id = $25
+		WHERE id = $16 AND user_id = $17
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare update statement: %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
+	result, err := stmt.Exec(
 		trade.Ticker, trade.Direction, trade.EntryPrice, trade.ExitPrice, trade.Quantity,
 		trade.TradeDate, trade.EntryTime, trade.ExitTime, trade.StopLoss, trade.TakeProfit,
 		trade.Commissions, trade.HighestPrice, trade.LowestPrice, trade.Notes, trade.ScreenshotURL,
-		trade.ID,
+		trade.ID, userID, trade.AccountID, trade.RootSymbol, trade.Strategy, trade.Currency, trade.Status,
+		trade.PlannedRisk, trade.PlannedRiskTicks, trade.StrategyID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update trade: %w", err)
 	}
+	if err := expectAffected(result, "trade", trade.ID); err != nil {
+		return err
+	}
 
+	// legs are only replaced when they're sent, so a trade without them keeps its legs
+	if trade.Legs != nil {
+		return ReplaceOptionLegs(db, userID, trade.ID, trade.Legs)
+	}
+	return nil
+}
+
+// tradeCurrency is the trade's own currency if it has one, otherwise the currency the
+// instrument is quoted in, or the account's for anything that isn't futures
+func tradeCurrency(db DbExecutor, trade Trade) (string, error) {
+	if currency := strings.ToUpper(strings.TrimSpace(trade.Currency)); currency != "" {
+		if len(currency) != 3 {
+			return "", errors.New("currency must be a 3 letter code")
+		}
+		return currency, nil
+	}
+
+	instrument, isFutures, err := FindInstrument(db, trade.UserID, trade.RootSymbol)
+	if err != nil {
+		return "", err
+	}
+	if isFutures {
+		return instrument.Currency, nil
+	}
+
+	var currency string
+	err = db.QueryRow("SELECT currency FROM accounts WHERE id = $1", trade.AccountID).Scan(&currency)
+	if err != nil {
+		return "", fmt.Errorf("failed to get account currency: %w", err)
+	}
+	return currency, nil
+}
+
+// checkTradeOwner returns ErrNotFound unless the trade exists and belongs to the user
+func checkTradeOwner(db DbExecutor, userID, tradeID int) error {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM trades WHERE id = $1 AND user_id = $2)", tradeID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check trade: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("trade with ID %d %w", tradeID, ErrNotFound)
+	}
+	return nil
+}
+
+// expectAffected turns an update or delete that didn't touch any rows into ErrNotFound
+func expectAffected(result sql.Result, name string, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s with ID %d %w", name, id, ErrNotFound)
+	}
 	return nil
 }
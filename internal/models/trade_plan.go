@@ -0,0 +1,371 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTradeAlreadyPlanned = errors.New("trade already has a plan")
+	ErrPlanNotLinked       = errors.New("trade plan isn't linked to a trade")
+	ErrPlanTradeMismatch   = errors.New("trade doesn't match the plan's ticker and direction")
+)
+
+// TradePlan is what was planned before a trade: the thesis, the entry, the stop, the target and
+// the size. it can be written before the trade exists and linked to it once it's taken
+type TradePlan struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	TradeID     *int      `json:"trade_id"`
+	Ticker      string    `json:"ticker"`
+	Direction   string    `json:"direction"`
+	Thesis      *string   `json:"thesis"`
+	EntryPrice  float64   `json:"entry_price"`
+	StopLoss    *float64  `json:"stop_loss"`
+	TargetPrice *float64  `json:"target_price"`
+	Quantity    float64   `json:"quantity"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PlanComparison is how a trade was executed against its plan
+type PlanComparison struct {
+	Plan  TradePlan `json:"plan"`
+	Trade Trade     `json:"trade"`
+	// how much worse the fill was than the planned entry, in price and in the trade's currency.
+	// negative when it was better
+	EntrySlippage       float64 `json:"entry_slippage"`
+	EntrySlippageAmount float64 `json:"entry_slippage_amount"`
+	DirectionMatched    bool    `json:"direction_matched"`
+	// the trade didn't go past the planned stop, by the highest or lowest price when the trade
+	// has them and by the exit otherwise, or it was closed at the stop when it did. nil without
+	// a planned stop or an exit
+	StopRespected *bool `json:"stop_respected"`
+	// the price reached the target, by the highest or lowest price when the trade has them and
+	// by the exit otherwise. nil without a target or an exit
+	TargetHit      *bool   `json:"target_hit"`
+	SizeMatched    bool    `json:"size_matched"`
+	SizeDifference float64 `json:"size_difference"` // traded minus planned
+}
+
+// PlanReport compares every linked plan with its trade. the rates only count the plans they
+// apply to, a plan without a stop has no stop adherence
+type PlanReport struct {
+	Plans             int              `json:"plans"`
+	Unlinked          int              `json:"unlinked"` // plans that were never traded, or not yet
+	StopRespectedRate float64          `json:"stop_respected_rate"`
+	TargetHitRate     float64          `json:"target_hit_rate"`
+	SizeMatchedRate   float64          `json:"size_matched_rate"`
+	Comparisons       []PlanComparison `json:"comparisons"`
+}
+
+// ValidateTradePlan normalises the fields and checks the ones the trade_plans table constrains
+func ValidateTradePlan(plan *TradePlan) error {
+	plan.Ticker = strings.ToUpper(strings.TrimSpace(plan.Ticker))
+	if plan.Ticker == "" {
+		return errors.New("ticker is required")
+	}
+	plan.Direction = strings.ToUpper(strings.TrimSpace(plan.Direction))
+	if plan.Direction != "LONG" && plan.Direction != "SHORT" {
+		return errors.New("direction must be LONG or SHORT")
+	}
+	if plan.EntryPrice < 0 {
+		return errors.New("entry price can't be negative")
+	}
+	if plan.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	// the stop and the target have to be on their side of the entry
+	if plan.StopLoss != nil {
+		if (plan.Direction == "LONG" && *plan.StopLoss >= plan.EntryPrice) || (plan.Direction == "SHORT" && *plan.StopLoss <= plan.EntryPrice) {
+			return errors.New("stop loss is on the wrong side of the entry")
+		}
+	}
+	if plan.TargetPrice != nil {
+		if (plan.Direction == "LONG" && *plan.TargetPrice <= plan.EntryPrice) || (plan.Direction == "SHORT" && *plan.TargetPrice >= plan.EntryPrice) {
+			return errors.New("target is on the wrong side of the entry")
+		}
+	}
+	return nil
+}
+
+func CreateTradePlan(db DbExecutor, plan *TradePlan) error {
+	if plan.TradeID != nil {
+		if err := checkPlanMatchesTrade(db, *plan, *plan.TradeID); err != nil {
+			return err
+		}
+	}
+	err := db.QueryRow(`
+		INSERT INTO trade_plans (user_id, trade_id, ticker, direction, thesis, entry_price, stop_loss, target_price, quantity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, plan.UserID, plan.TradeID, plan.Ticker, plan.Direction, plan.Thesis, plan.EntryPrice, plan.StopLoss,
+		plan.TargetPrice, plan.Quantity).Scan(&plan.ID, &plan.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTradeAlreadyPlanned
+		}
+		log.Printf("Error creating trade plan: %v", err)
+		return fmt.Errorf("failed to create trade plan: %w", err)
+	}
+	return nil
+}
+
+const tradePlanColumns = `id, user_id, trade_id, ticker, direction, thesis, entry_price, stop_loss, target_price,
+	quantity, created_at`
+
+func scanTradePlan(row interface{ Scan(...interface{}) error }) (TradePlan, error) {
+	var p TradePlan
+	err := row.Scan(&p.ID, &p.UserID, &p.TradeID, &p.Ticker, &p.Direction, &p.Thesis, &p.EntryPrice, &p.StopLoss,
+		&p.TargetPrice, &p.Quantity, &p.CreatedAt)
+	return p, err
+}
+
+// get a trade plan, as long as it belongs to the user
+func GetTradePlan(db DbExecutor, userID, id int) (TradePlan, error) {
+	plan, err := scanTradePlan(db.QueryRow("SELECT "+tradePlanColumns+" FROM trade_plans WHERE id = $1 AND user_id = $2", id, userID))
+	if err == sql.ErrNoRows {
+		return plan, fmt.Errorf("trade plan with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return plan, fmt.Errorf("failed to get trade plan: %w", err)
+	}
+	return plan, nil
+}
+
+// ListTradePlans returns the user's plans, newest first. linked picks the plans that were or
+// weren't traded, nil returns them all
+func ListTradePlans(db DbExecutor, userID int, linked *bool) ([]TradePlan, error) {
+	query := "SELECT " + tradePlanColumns + " FROM trade_plans WHERE user_id = $1"
+	if linked != nil && *linked {
+		query += " AND trade_id IS NOT NULL"
+	} else if linked != nil {
+		query += " AND trade_id IS NULL"
+	}
+	rows, err := db.Query(query+" ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve trade plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []TradePlan{}
+	for rows.Next() {
+		plan, err := scanTradePlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trade plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trade plans: %w", err)
+	}
+	return plans, nil
+}
+
+// UpdateTradePlan changes what was planned, the link to the trade goes through LinkTradePlan. a
+// linked plan has to keep matching its trade
+func UpdateTradePlan(db DbExecutor, plan *TradePlan) error {
+	current, err := GetTradePlan(db, plan.UserID, plan.ID)
+	if err != nil {
+		return err
+	}
+	if current.TradeID != nil {
+		if err := checkPlanMatchesTrade(db, *plan, *current.TradeID); err != nil {
+			return err
+		}
+	}
+	err = db.QueryRow(`
+		UPDATE trade_plans
+		SET ticker = $1, direction = $2, thesis = $3, entry_price = $4, stop_loss = $5, target_price = $6, quantity = $7
+		WHERE id = $8 AND user_id = $9
+		RETURNING trade_id, created_at
+	`, plan.Ticker, plan.Direction, plan.Thesis, plan.EntryPrice, plan.StopLoss, plan.TargetPrice, plan.Quantity,
+		plan.ID, plan.UserID).Scan(&plan.TradeID, &plan.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("trade plan with ID %d %w", plan.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update trade plan: %w", err)
+	}
+	return nil
+}
+
+// LinkTradePlan links a plan to the trade that was taken from it, or unlinks it when tradeID is nil
+func LinkTradePlan(db DbExecutor, userID, id int, tradeID *int) error {
+	if tradeID != nil {
+		plan, err := GetTradePlan(db, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := checkPlanMatchesTrade(db, plan, *tradeID); err != nil {
+			return err
+		}
+	}
+	result, err := db.Exec("UPDATE trade_plans SET trade_id = $1 WHERE id = $2 AND user_id = $3", tradeID, id, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTradeAlreadyPlanned
+		}
+		return fmt.Errorf("failed to link trade plan: %w", err)
+	}
+	return expectAffected(result, "trade plan", id)
+}
+
+// checkPlanMatchesTrade makes sure the trade is the user's and is the one the plan was for. a
+// plan for a root symbol (ES) matches a trade in any of its contracts (ESM5)
+func checkPlanMatchesTrade(db DbExecutor, plan TradePlan, tradeID int) error {
+	trade, err := GetTrade(db, plan.UserID, tradeID)
+	if err != nil {
+		return err
+	}
+	ticker := strings.ToUpper(strings.TrimSpace(plan.Ticker))
+	tickerMatched := ticker == strings.ToUpper(trade.Ticker) || RootSymbol(ticker, trade.EntryTime) == trade.RootSymbol
+	if !tickerMatched || strings.ToUpper(plan.Direction) != strings.ToUpper(trade.Direction) {
+		return fmt.Errorf("%w: planned %s %s, traded %s %s", ErrPlanTradeMismatch, plan.Direction, plan.Ticker,
+			trade.Direction, trade.Ticker)
+	}
+	return nil
+}
+
+func DeleteTradePlan(db DbExecutor, userID, id int) error {
+	result, err := db.Exec("DELETE FROM trade_plans WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete trade plan: %w", err)
+	}
+	return expectAffected(result, "trade plan", id)
+}
+
+// ComparePlan compares a linked plan with its trade
+func ComparePlan(db DbExecutor, plan TradePlan) (PlanComparison, error) {
+	comparison := PlanComparison{Plan: plan}
+	if plan.TradeID == nil {
+		return comparison, ErrPlanNotLinked
+	}
+	trade, err := GetTrade(db, plan.UserID, *plan.TradeID)
+	if err != nil {
+		return comparison, err
+	}
+	comparison.Trade = trade
+	comparison.DirectionMatched = strings.ToUpper(trade.Direction) == plan.Direction
+
+	// slippage is what the plan would have lost by getting the actual fill
+	comparison.EntrySlippage = trade.EntryPrice - plan.EntryPrice
+	if plan.Direction == "SHORT" {
+		comparison.EntrySlippage = -comparison.EntrySlippage
+	}
+	instrument, isFutures, err := FindInstrument(db, plan.UserID, trade.RootSymbol)
+	if err != nil {
+		return comparison, err
+	}
+	comparison.EntrySlippageAmount = priceProfitLoss(plan.Direction, plan.EntryPrice, trade.EntryPrice, trade.Quantity, instrument, isFutures)
+	if len(trade.Legs) > 0 {
+		comparison.EntrySlippageAmount *= trade.Legs[0].Multiplier
+	}
+
+	comparison.SizeDifference = trade.Quantity - plan.Quantity
+	comparison.SizeMatched = comparison.SizeDifference == 0
+
+	if trade.ExitPrice == nil {
+		return comparison, nil
+	}
+	exit := *trade.ExitPrice
+	if plan.StopLoss != nil {
+		tickSize := 0.01
+		if isFutures && instrument.TickSize > 0 {
+			tickSize = instrument.TickSize
+		}
+		respected := stopRespected(plan.Direction, *plan.StopLoss, exit, trade.HighestPrice, trade.LowestPrice, tickSize)
+		comparison.StopRespected = &respected
+	}
+	if plan.TargetPrice != nil {
+		// the best price the trade saw
+		best := exit
+		var hit bool
+		if plan.Direction == "SHORT" {
+			if trade.LowestPrice != nil {
+				best = min(best, *trade.LowestPrice)
+			}
+			hit = best <= *plan.TargetPrice
+		} else {
+			if trade.HighestPrice != nil {
+				best = max(best, *trade.HighestPrice)
+			}
+			hit = best >= *plan.TargetPrice
+		}
+		comparison.TargetHit = &hit
+	}
+	return comparison, nil
+}
+
+// stopRespected tells whether a trade kept to its stop. it broke it when its worst price went
+// past the stop and it wasn't closed there, a fill up to a tick beyond the stop is slippage
+func stopRespected(direction string, stop, exit float64, highest, lowest *float64, tickSize float64) bool {
+	worst := exit
+	var past bool
+	if direction == "SHORT" {
+		if highest != nil {
+			worst = max(worst, *highest)
+		}
+		past = worst > stop
+	} else {
+		if lowest != nil {
+			worst = min(worst, *lowest)
+		}
+		past = worst < stop
+	}
+	return !past || math.Abs(exit-stop) <= tickSize*(1+1e-9)
+}
+
+// GetPlanReport compares every linked plan of the user with its trade
+func GetPlanReport(db DbExecutor, userID int) (PlanReport, error) {
+	report := PlanReport{Comparisons: []PlanComparison{}}
+	plans, err := ListTradePlans(db, userID, nil)
+	if err != nil {
+		return report, err
+	}
+
+	var stops, stopsRespected, targets, targetsHit, sizesMatched int
+	for _, plan := range plans {
+		if plan.TradeID == nil {
+			report.Unlinked++
+			continue
+		}
+		comparison, err := ComparePlan(db, plan)
+		if err != nil {
+			return report, err
+		}
+		report.Comparisons = append(report.Comparisons, comparison)
+
+		if comparison.StopRespected != nil {
+			stops++
+			if *comparison.StopRespected {
+				stopsRespected++
+			}
+		}
+		if comparison.TargetHit != nil {
+			targets++
+			if *comparison.TargetHit {
+				targetsHit++
+			}
+		}
+		if comparison.SizeMatched {
+			sizesMatched++
+		}
+	}
+
+	report.Plans = len(report.Comparisons)
+	if stops > 0 {
+		report.StopRespectedRate = float64(stopsRespected) / float64(stops)
+	}
+	if targets > 0 {
+		report.TargetHitRate = float64(targetsHit) / float64(targets)
+	}
+	if report.Plans > 0 {
+		report.SizeMatchedRate = float64(sizesMatched) / float64(report.Plans)
+	}
+	return report, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStopRespected(t *testing.T) {
+	price := func(f float64) *float64 { return &f }
+	tests := []struct {
+		name      string
+		direction string
+		stop      float64
+		exit      float64
+		highest   *float64
+		lowest    *float64
+		want      bool
+	}{
+		{"long never near the stop", "LONG", 95, 110, price(112), price(98), true},
+		{"long stopped out at the stop", "LONG", 95, 95, price(104), price(95), true},
+		{"long stopped out a tick through", "LONG", 95, 94.99, price(104), price(94.99), true},
+		{"long went through the stop and came back", "LONG", 95, 103, price(104), price(90), false},
+		{"long held well past the stop", "LONG", 95, 90, price(101), price(89), false},
+		{"long without excursion data, exit past the stop", "LONG", 95, 93, nil, nil, false},
+		{"long without excursion data, exit above the stop", "LONG", 95, 99, nil, nil, true},
+		{"short never near the stop", "SHORT", 105, 90, price(103), price(88), true},
+		{"short stopped out at the stop", "SHORT", 105, 105, price(105), price(97), true},
+		{"short went through the stop and came back", "SHORT", 105, 98, price(110), price(96), false},
+	}
+	for _, tt := range tests {
+		if got := stopRespected(tt.direction, tt.stop, tt.exit, tt.highest, tt.lowest, 0.01); got != tt.want {
+			t.Errorf("%s: stopRespected() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateLinkedTradePlan(t *testing.T) {
+	db := openTestDB(t)
+	userID, accountID := createTestUser(t, db)
+	trade := addTestTrade(t, db, userID, accountID, 105, 100)
+
+	plan := TradePlan{UserID: userID, TradeID: &trade.ID, Ticker: "AAPL", Direction: "LONG", EntryPrice: 100, Quantity: 100}
+	if err := CreateTradePlan(db, &plan); err != nil {
+		t.Fatalf("CreateTradePlan() error = %v", err)
+	}
+
+	// a linked plan can't be turned into a plan for another ticker or direction
+	for _, change := range []struct{ ticker, direction string }{{"MSFT", "LONG"}, {"AAPL", "SHORT"}} {
+		changed := plan
+		changed.Ticker = change.ticker
+		changed.Direction = change.direction
+		if err := UpdateTradePlan(db, &changed); !errors.Is(err, ErrPlanTradeMismatch) {
+			t.Errorf("UpdateTradePlan() to %s %s error = %v, want ErrPlanTradeMismatch", change.direction, change.ticker, err)
+		}
+	}
+	stored, err := GetTradePlan(db, userID, plan.ID)
+	if err != nil {
+		t.Fatalf("GetTradePlan() error = %v", err)
+	}
+	if stored.Ticker != "AAPL" || stored.Direction != "LONG" {
+		t.Errorf("plan was changed to %s %s", stored.Direction, stored.Ticker)
+	}
+
+	// the rest of the plan can still change
+	changed := plan
+	changed.EntryPrice = 99
+	if err := UpdateTradePlan(db, &changed); err != nil {
+		t.Errorf("UpdateTradePlan() of the entry error = %v", err)
+	}
+
+	// and an unlinked plan can be for anything
+	if err := LinkTradePlan(db, userID, plan.ID, nil); err != nil {
+		t.Fatalf("LinkTradePlan() error = %v", err)
+	}
+	changed.TradeID = nil
+	changed.Ticker = "MSFT"
+	changed.Direction = "SHORT"
+	changed.EntryPrice = 100
+	if err := UpdateTradePlan(db, &changed); err != nil {
+		t.Errorf("UpdateTradePlan() of an unlinked plan error = %v", err)
+	}
+}
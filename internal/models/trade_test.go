@@ -0,0 +1,17 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestListTradesRejectsUnknownSortField(t *testing.T) {
+	// the sort field ends up in the query, anything that isn't a known column must be refused
+	// before the database is ever asked
+	for _, sortBy := range []string{"id; DROP TABLE trades", "t.notes", "(SELECT 1)", "net_profit_loss"} {
+		_, err := ListTrades(nil, 1, TradeFilter{SortBy: sortBy})
+		if !errors.Is(err, ErrUnknownSortField) {
+			t.Errorf("ListTrades() sorted by %q error = %v, want ErrUnknownSortField", sortBy, err)
+		}
+	}
+}
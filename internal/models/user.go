@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidTimezone    = errors.New("unknown timezone")
+)
+
+type User struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	Timezone     string    `json:"timezone"` // IANA name, days and hours in reports are in this zone
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateUser hashes the password with bcrypt and stores the new user
+func CreateUser(db DbExecutor, email, password string) (User, error) {
+	user := User{Email: normalizeEmail(email)}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = string(hash)
+
+	err = db.QueryRow(`
+		INSERT INTO users (email, password_hash) VALUES ($1, $2)
+		RETURNING id, timezone, created_at
+	`, user.Email, user.PasswordHash).Scan(&user.ID, &user.Timezone, &user.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return user, ErrEmailTaken
+		}
+		log.Printf("Error creating user: %v", err)
+		return user, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// AuthenticateUser checks an email and password and returns the matching user.
+// a wrong email and a wrong password give the same error so emails can't be probed
+func AuthenticateUser(db DbExecutor, email, password string) (User, error) {
+	var user User
+	err := db.QueryRow(`
+		SELECT id, email, password_hash, timezone, created_at FROM users WHERE email = $1
+	`, normalizeEmail(email)).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Timezone, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, ErrInvalidCredentials
+	}
+	if err != nil {
+		return user, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func GetUserByID(db DbExecutor, id int) (User, error) {
+	var user User
+	err := db.QueryRow(`
+		SELECT id, email, password_hash, timezone, created_at FROM users WHERE id = $1
+	`, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Timezone, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, fmt.Errorf("user with ID %d not found", id)
+	}
+	if err != nil {
+		return user, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateUserTimezone sets the timezone reports group trades by. it has to be a name from the
+// IANA database, like Europe/Berlin
+func UpdateUserTimezone(db DbExecutor, id int, timezone string) error {
+	timezone = strings.TrimSpace(timezone)
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return fmt.Errorf("%w: %q", ErrInvalidTimezone, timezone)
+	}
+	_, err := db.Exec("UPDATE users SET timezone = $1 WHERE id = $2", timezone, id)
+	if err != nil {
+		return fmt.Errorf("failed to update timezone: %w", err)
+	}
+	return nil
+}
+
+// userTimezone is the timezone of the user's reports
+func userTimezone(db DbExecutor, id int) (string, error) {
+	var timezone string
+	err := db.QueryRow("SELECT timezone FROM users WHERE id = $1", id).Scan(&timezone)
+	if err != nil {
+		return "", fmt.Errorf("failed to get timezone: %w", err)
+	}
+	return timezone, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// trade fields the generic csv importer can fill in, and whether a column for them is required
+var csvImportFields = []struct {
+	name     string
+	required bool
+}{
+	{"ticker", true},
+	{"direction", false},
+	{"entry_price", true},
+	{"exit_price", true},
+	{"quantity", true},
+	{"entry_time", true},
+	{"exit_time", true},
+	{"trade_date", false},
+	{"stop_loss", false},
+	{"take_profit", false},
+	{"commissions", false},
+	{"highest_price", false},
+	{"lowest_price", false},
+	{"notes", false},
+	{"account", false},
+}
+
+// ColumnMappedCSVImporter reads any csv with one round trip per row. the caller tells it
+// which header holds which trade field, so it works for brokers we don't have a parser for
+type ColumnMappedCSVImporter struct{}
+
+func NewColumnMappedCSVImporter() *ColumnMappedCSVImporter {
+	return &ColumnMappedCSVImporter{}
+}
+
+func (c *ColumnMappedCSVImporter) Import(r io.Reader, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{Format: "csv"}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return result, errors.New("csv file is empty")
+	}
+
+	location, err := options.location()
+	if err != nil {
+		return result, err
+	}
+	columns := headerIndex(records[0])
+	mapping, err := c.resolveColumns(columns, options.Columns)
+	if err != nil {
+		return result, err
+	}
+	if _, ok := mapping["direction"]; !ok {
+		result.Warnings = append(result.Warnings, ImportWarning{
+			Message: "no direction column, trades with a negative quantity are imported as SHORT and the rest as LONG",
+		})
+	}
+
+	rows := records[1:]
+	result.TotalRows = len(rows)
+	for i, record := range rows {
+		rowNumber := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+
+		trade, warnings, err := c.tradeFromRow(mapping, columns, record, options.TimeLayout, location)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		for _, warning := range warnings {
+			result.Warnings = append(result.Warnings, ImportWarning{Row: rowNumber, Message: warning})
+		}
+		account := ""
+		if header, ok := mapping["account"]; ok {
+			account = columns.get(record, header)
+		}
+		result.Trades = append(result.Trades, ImportedTrade{Row: rowNumber, Account: account, Trade: trade})
+	}
+
+	return result, nil
+}
+
+// work out which header each trade field comes from. fields that aren't in the mapping
+// fall back to a header with the same name, so a file that already uses our field names
+// can be imported without any mapping at all
+func (c *ColumnMappedCSVImporter) resolveColumns(columns columnIndex, configured map[string]string) (map[string]string, error) {
+	known := make(map[string]bool, len(csvImportFields))
+	for _, field := range csvImportFields {
+		known[field.name] = true
+	}
+	for field := range configured {
+		if !known[field] {
+			return nil, fmt.Errorf("unknown trade field %q in column mapping", field)
+		}
+	}
+
+	mapping := make(map[string]string)
+	var missing []string
+	for _, field := range csvImportFields {
+		header := strings.ToLower(strings.TrimSpace(configured[field.name]))
+		if header == "" {
+			header = field.name
+		}
+		if columns.has(header) {
+			mapping[field.name] = header
+			continue
+		}
+		if configured[field.name] != "" {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the file", configured[field.name], field.name)
+		}
+		if field.required {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no column mapped for required fields: %s", strings.Join(missing, ", "))
+	}
+	return mapping, nil
+}
+
+func (c *ColumnMappedCSVImporter) tradeFromRow(mapping map[string]string, columns columnIndex, record []string, timeLayout string, location *time.Location) (models.Trade, []string, error) {
+	var trade models.Trade
+	var warnings []string
+
+	value := func(field string) string {
+		header, ok := mapping[field]
+		if !ok {
+			return ""
+		}
+		return columns.get(record, header)
+	}
+
+	trade.Ticker = strings.ToUpper(value("ticker"))
+	if trade.Ticker == "" {
+		return trade, nil, errors.New("missing ticker")
+	}
+
+	var err error
+	if trade.Quantity, err = parseNumber(value("quantity")); err != nil {
+		return trade, nil, fmt.Errorf("invalid quantity: %w", err)
+	}
+	if trade.EntryPrice, err = parseNumber(value("entry_price")); err != nil {
+		return trade, nil, fmt.Errorf("invalid entry_price: %w", err)
+	}
+	exitPrice, err := parseNumber(value("exit_price"))
+	if err != nil {
+		return trade, nil, fmt.Errorf("invalid exit_price: %w", err)
+	}
+	trade.ExitPrice = &exitPrice
+	if trade.EntryTime, err = parseTimestampLayout(value("entry_time"), timeLayout, location); err != nil {
+		return trade, nil, fmt.Errorf("invalid entry_time: %w", err)
+	}
+	exitTime, err := parseTimestampLayout(value("exit_time"), timeLayout, location)
+	if err != nil {
+		return trade, nil, fmt.Errorf("invalid exit_time: %w", err)
+	}
+	trade.ExitTime = &exitTime
+	trade.TradeDate = trade.EntryTime
+	if s := value("trade_date"); s != "" {
+		if trade.TradeDate, err = parseTimestampLayout(s, timeLayout, location); err != nil {
+			return trade, nil, fmt.Errorf("invalid trade_date: %w", err)
+		}
+	}
+
+	// brokers write direction in all sorts of ways
+	switch strings.ToUpper(value("direction")) {
+	case "LONG", "BUY", "B", "BOT", "BOUGHT":
+		trade.Direction = "LONG"
+	case "SHORT", "SELL", "S", "SLD", "SOLD", "SELL SHORT":
+		trade.Direction = "SHORT"
+	case "":
+		trade.Direction = "LONG"
+		if trade.Quantity < 0 {
+			trade.Direction = "SHORT"
+		}
+	default:
+		return trade, nil, fmt.Errorf("invalid direction %q", value("direction"))
+	}
+	if trade.Quantity < 0 {
+		if value("direction") != "" {
+			warnings = append(warnings, "negative quantity with a direction, using the absolute quantity")
+		}
+		trade.Quantity = math.Abs(trade.Quantity)
+	}
+
+	// optional prices
+	optional := []struct {
+		field string
+		dest  **float64
+	}{
+		{"stop_loss", &trade.StopLoss},
+		{"take_profit", &trade.TakeProfit},
+		{"highest_price", &trade.HighestPrice},
+		{"lowest_price", &trade.LowestPrice},
+	}
+	for _, o := range optional {
+		if s := value(o.field); s != "" {
+			f, err := parseNumber(s)
+			if err != nil {
+				return trade, nil, fmt.Errorf("invalid %s: %w", o.field, err)
+			}
+			*o.dest = &f
+		}
+	}
+
+	if s := value("commissions"); s != "" {
+		commissions, err := parseNumber(s)
+		if err != nil {
+			return trade, nil, fmt.Errorf("invalid commissions: %w", err)
+		}
+		if commissions < 0 {
+			warnings = append(warnings, "negative commissions, treating them as a cost")
+			commissions = math.Abs(commissions)
+		}
+		trade.Commissions = &commissions
+	}
+
+	if notes := value("notes"); notes != "" {
+		trade.Notes = &notes
+	}
+
+	if trade.HighestPrice != nil && trade.LowestPrice != nil && *trade.HighestPrice < *trade.LowestPrice {
+		warnings = append(warnings, "highest_price is below lowest_price")
+	}
+
+	return trade, warnings, validateImportedTrade(trade)
+}
+
+// parse a timestamp with the layout the caller gave us, or the usual layouts if they didn't.
+// like parseTimestamp it reads zoneless times in location and returns UTC
+func parseTimestampLayout(s, layout string, location *time.Location) (time.Time, error) {
+	if layout == "" {
+		return parseTimestamp(s, location)
+	}
+	t, err := time.ParseInLocation(layout, strings.TrimSpace(s), location)
+	return t.UTC(), err
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestColumnMappedCSVImporterMappedHeaders(t *testing.T) {
+	options := ImportOptions{
+		Columns: map[string]string{
+			"ticker":      "Symbol",
+			"direction":   "Side",
+			"quantity":    "Qty",
+			"entry_price": "Open Price",
+			"exit_price":  "Close Price",
+			"entry_time":  "Opened",
+			"exit_time":   "Closed",
+			"commissions": "Fees",
+			"stop_loss":   "STOP", // headers match without regard to case
+			"notes":       "Comment",
+			"account":     "Acct",
+		},
+		TimeLayout: "01/02/2006 15:04",
+	}
+	result, err := NewColumnMappedCSVImporter().Import(openFixture(t, "generic_broker.csv"), options)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if result.TotalRows != 7 {
+		t.Errorf("TotalRows = %d, want 7", result.TotalRows)
+	}
+	if got := importedRows(result.Trades); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Fatalf("imported rows = %v, want [2 3]", got)
+	}
+
+	aapl := result.Trades[0]
+	if aapl.Account != "U123" || aapl.Trade.Ticker != "AAPL" || aapl.Trade.Direction != "LONG" {
+		t.Errorf("row 2: %q %s %s, want U123 AAPL LONG", aapl.Account, aapl.Trade.Ticker, aapl.Trade.Direction)
+	}
+	if !approxEqual(aapl.Trade.EntryPrice, 1187.50) || !approxEqual(*aapl.Trade.ExitPrice, 1190.25) || !approxEqual(aapl.Trade.Quantity, 100) {
+		t.Errorf("row 2: entry %v exit %v quantity %v, want 1187.50 1190.25 100", aapl.Trade.EntryPrice, *aapl.Trade.ExitPrice, aapl.Trade.Quantity)
+	}
+	if !aapl.Trade.EntryTime.Equal(mustTime(t, "2025-05-01T09:35:00Z")) || !aapl.Trade.ExitTime.Equal(mustTime(t, "2025-05-01T10:05:00Z")) {
+		t.Errorf("row 2: entry %v exit %v", aapl.Trade.EntryTime, aapl.Trade.ExitTime)
+	}
+	if aapl.Trade.StopLoss == nil || !approxEqual(*aapl.Trade.StopLoss, 1180) {
+		t.Errorf("row 2: stop loss %v, want 1180", aapl.Trade.StopLoss)
+	}
+	if aapl.Trade.Notes == nil || *aapl.Trade.Notes != "breakout" {
+		t.Errorf("row 2: notes %v, want breakout", aapl.Trade.Notes)
+	}
+
+	// a negative quantity next to a direction and fees in parentheses are both cleaned up with a warning
+	msft := result.Trades[1].Trade
+	if msft.Direction != "SHORT" || !approxEqual(msft.Quantity, 50) {
+		t.Errorf("row 3: %s %v, want SHORT 50", msft.Direction, msft.Quantity)
+	}
+	if msft.Commissions == nil || !approxEqual(*msft.Commissions, 2) {
+		t.Errorf("row 3: commissions %v, want 2", msft.Commissions)
+	}
+	if msft.StopLoss != nil || msft.Notes != nil {
+		t.Errorf("row 3: empty optional columns should stay nil, got stop %v notes %v", msft.StopLoss, msft.Notes)
+	}
+	if len(result.Warnings) != 2 || result.Warnings[0].Row != 3 || result.Warnings[1].Row != 3 {
+		t.Errorf("warnings = %+v, want two for row 3", result.Warnings)
+	}
+
+	wantErrors := []struct {
+		row     int
+		message string
+	}{
+		{4, "invalid entry_price"},
+		{6, "invalid direction"},
+		{7, "exit time is before entry time"},
+		{8, "invalid entry_time"},
+	}
+	if got := errorRows(result.Errors); !reflect.DeepEqual(got, []int{4, 6, 7, 8}) {
+		t.Fatalf("error rows = %v, want [4 6 7 8]", got)
+	}
+	for i, want := range wantErrors {
+		if !strings.Contains(result.Errors[i].Error, want.message) {
+			t.Errorf("row %d error = %q, want it to mention %q", want.row, result.Errors[i].Error, want.message)
+		}
+	}
+}
+
+func TestColumnMappedCSVImporterOwnFieldNames(t *testing.T) {
+	// a file that already uses the trade field names needs no mapping
+	result, err := NewColumnMappedCSVImporter().Import(openFixture(t, "generic_fields.csv"), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("errors = %+v, want none", result.Errors)
+	}
+	if got := importedRows(result.Trades); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Fatalf("imported rows = %v, want [2 3]", got)
+	}
+	// without a direction column the sign of the quantity decides
+	if len(result.Warnings) != 1 || result.Warnings[0].Row != 0 {
+		t.Errorf("warnings = %+v, want one for the whole file", result.Warnings)
+	}
+
+	es := result.Trades[0].Trade
+	if es.Ticker != "ES" || es.Direction != "LONG" || !approxEqual(es.Quantity, 2) || !approxEqual(*es.Commissions, 4.12) {
+		t.Errorf("row 2: %s %s %v %v, want ES LONG 2 4.12", es.Ticker, es.Direction, es.Quantity, *es.Commissions)
+	}
+	if !es.EntryTime.Equal(mustTime(t, "2025-05-01T09:31:05Z")) || !es.TradeDate.Equal(es.EntryTime) {
+		t.Errorf("row 2: entry %v trade date %v", es.EntryTime, es.TradeDate)
+	}
+	nq := result.Trades[1].Trade
+	if nq.Direction != "SHORT" || !approxEqual(nq.Quantity, 1) || nq.Commissions != nil {
+		t.Errorf("row 3: %s %v commissions %v, want SHORT 1 nil", nq.Direction, nq.Quantity, nq.Commissions)
+	}
+	if !nq.ExitTime.Equal(mustTime(t, "2025-05-01T10:20:30Z")) {
+		t.Errorf("row 3: exit %v", nq.ExitTime)
+	}
+}
+
+func TestColumnMappedCSVImporterBadMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		columns map[string]string
+		message string
+	}{
+		{"unknown field", "generic_fields.csv", map[string]string{"price": "EXIT_PRICE"}, "unknown trade field"},
+		{"mapped header missing", "generic_fields.csv", map[string]string{"ticker": "Symbol"}, "is not in the file"},
+		{"required field unmapped", "generic_broker.csv", nil, "no column mapped for required fields"},
+	}
+	for _, tt := range tests {
+		_, err := NewColumnMappedCSVImporter().Import(openFixture(t, tt.fixture), ImportOptions{Columns: tt.columns})
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: Import() error = %v, want it to mention %q", tt.name, err, tt.message)
+		}
+	}
+}
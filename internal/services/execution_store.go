@@ -0,0 +1,92 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"trading-journal/internal/models"
+)
+
+// MatchReport is what gets sent back after unmatched executions are grouped into trades
+type MatchReport struct {
+	Method        MatchingMethod `json:"method"`
+	TradeIDs      []int          `json:"trade_ids"`
+	OpenPositions []OpenPosition `json:"open_positions"`
+}
+
+// MatchExecutionsToTrades takes every execution that isn't part of a trade yet, groups them
+// into round trips and saves a trade (plus its metrics) for each one. the executions are
+// linked to the trade they ended up in, so the trades and trade_metrics rows always come
+// from the fills. everything happens in one transaction
+func MatchExecutionsToTrades(db *sql.DB, userID int, method MatchingMethod) (MatchReport, error) {
+	report := MatchReport{Method: method, TradeIDs: []int{}, OpenPositions: []OpenPosition{}}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	executions, err := models.GetUnmatchedExecutions(tx, userID)
+	if err != nil {
+		return report, err
+	}
+
+	trips, positions := BuildRoundTrips(executions, method)
+	for _, trip := range trips {
+		trade := trip.Trade
+		trade.UserID = userID
+
+		id, err := models.AddTrade(tx, trade)
+		if err != nil {
+			return report, err
+		}
+		trade.ID = id
+		if err := models.CalculateAndInsertTradeMetrics(tx, trade); err != nil {
+			return report, err
+		}
+
+		if err := saveExecutionPieces(tx, userID, trip.Executions, &id); err != nil {
+			return report, err
+		}
+		report.TradeIDs = append(report.TradeIDs, id)
+	}
+
+	// fills that are still open stay unmatched, but a fill that flipped the position
+	// leaves a new piece behind that still has to be saved
+	for _, position := range positions {
+		if err := saveExecutionPieces(tx, userID, position.Executions, nil); err != nil {
+			return report, err
+		}
+		report.OpenPositions = append(report.OpenPositions, position)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Matched executions for user %d into %d trades, %d positions still open", userID, len(report.TradeIDs), len(report.OpenPositions))
+	return report, nil
+}
+
+func saveExecutionPieces(tx *sql.Tx, userID int, executions []models.Execution, tradeID *int) error {
+	for _, execution := range executions {
+		execution.UserID = userID
+		execution.TradeID = tradeID
+		if execution.ID == 0 {
+			if _, err := models.AddExecution(tx, execution); err != nil {
+				return err
+			}
+			continue
+		}
+		if tradeID == nil {
+			// untouched fill of an open position
+			continue
+		}
+		if err := models.UpdateExecutionMatch(tx, userID, execution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// TradeFingerprint identifies an imported trade so re-uploading the same statement doesn't
+// create it twice. it hashes where the trade came from and the fields a broker won't change
+// between exports, formatted so that e.g. 5000.5 and 5000.50 give the same result
+func TradeFingerprint(source, account string, trade models.Trade) string {
+	price := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 6, 64)
+	}
+	// imported trades are always closed, an open one just fingerprints without its exit
+	var exitTime, exitPrice string
+	if trade.ExitTime != nil {
+		exitTime = trade.ExitTime.UTC().Format(time.RFC3339)
+	}
+	if trade.ExitPrice != nil {
+		exitPrice = price(*trade.ExitPrice)
+	}
+	parts := []string{
+		strings.ToLower(strings.TrimSpace(source)),
+		strings.TrimSpace(account),
+		strings.ToUpper(strings.TrimSpace(trade.Ticker)),
+		strings.ToUpper(trade.Direction),
+		trade.EntryTime.UTC().Format(time.RFC3339),
+		exitTime,
+		price(trade.EntryPrice),
+		exitPrice,
+		price(trade.Quantity),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// legacyTradeFingerprint is the fingerprint a futures trade got when the NinjaTrader importer
+// still stored the root symbol as the ticker (ES instead of ES 06-25). ok is false when the
+// ticker already is a root symbol, so there's nothing different to look for
+func legacyTradeFingerprint(source, account string, trade models.Trade) (fingerprint string, ok bool) {
+	root := models.RootSymbol(trade.Ticker, trade.EntryTime)
+	if root == strings.ToUpper(strings.TrimSpace(trade.Ticker)) {
+		return "", false
+	}
+	trade.Ticker = root
+	return TradeFingerprint(source, account, trade), true
+}
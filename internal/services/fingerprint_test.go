@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"trading-journal/internal/models"
+)
+
+func TestTradeFingerprint(t *testing.T) {
+	entry := mustTime(t, "2025-05-01T14:30:00Z")
+	exit := mustTime(t, "2025-05-01T15:00:00Z")
+	exitPrice := 5010.5
+	trade := models.Trade{Ticker: "ES 06-25", Direction: "LONG", EntryPrice: 5000.25, ExitPrice: &exitPrice,
+		Quantity: 2, EntryTime: entry, ExitTime: &exit}
+
+	fingerprint := TradeFingerprint("ninjatrader", "Sim101", trade)
+
+	// formatting and case don't matter
+	same := trade
+	same.Ticker = " es 06-25"
+	samePrice := 5010.50
+	same.ExitPrice = &samePrice
+	if got := TradeFingerprint("NinjaTrader", "Sim101", same); got != fingerprint {
+		t.Errorf("reformatted trade fingerprints as %s, want %s", got, fingerprint)
+	}
+
+	// the contract does, the same prices and times in another expiry are a different trade
+	nextContract := trade
+	nextContract.Ticker = "ES 09-25"
+	if TradeFingerprint("ninjatrader", "Sim101", nextContract) == fingerprint {
+		t.Error("trades in different contracts have the same fingerprint")
+	}
+
+	// trades imported when the ticker was only the root symbol are still found
+	legacy, ok := legacyTradeFingerprint("ninjatrader", "Sim101", trade)
+	rootTrade := trade
+	rootTrade.Ticker = "ES"
+	if want := TradeFingerprint("ninjatrader", "Sim101", rootTrade); !ok || legacy != want {
+		t.Errorf("legacyTradeFingerprint() = %s, %v, want %s, true", legacy, ok, want)
+	}
+	if _, ok := legacyTradeFingerprint("ninjatrader", "Sim101", rootTrade); ok {
+		t.Error("legacyTradeFingerprint() of a root symbol ticker is ok, want no legacy fingerprint")
+	}
+}
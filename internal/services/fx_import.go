@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// FXImportReport is what came of importing a file of fx rates
+type FXImportReport struct {
+	TotalRows     int        `json:"total_rows"`
+	ImportedCount int        `json:"imported_count"`
+	FailedCount   int        `json:"failed_count"`
+	Errors        []RowError `json:"errors"`
+}
+
+// header names the fx csv accepts for each column, the first one found wins
+var (
+	fxDateHeaders  = []string{"as_of", "date", "time", "timestamp"}
+	fxBaseHeaders  = []string{"base_currency", "base", "from"}
+	fxQuoteHeaders = []string{"quote_currency", "quote", "to"}
+	fxPairHeaders  = []string{"pair", "symbol", "currency_pair"}
+	fxRateHeaders  = []string{"rate", "close", "price"}
+)
+
+// ParseFXRatesCSV reads fx rates from a csv with a date, a rate and either separate base and
+// quote columns or one pair column like EURUSD or EUR/USD. rows that can't be read are
+// returned as errors, the rest as rates
+func ParseFXRatesCSV(r io.Reader, userID int) ([]models.FXRate, int, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, 0, nil, errors.New("csv file is empty")
+	}
+
+	columns := headerIndex(records[0])
+	dateHeader := firstHeader(columns, fxDateHeaders)
+	rateHeader := firstHeader(columns, fxRateHeaders)
+	baseHeader := firstHeader(columns, fxBaseHeaders)
+	quoteHeader := firstHeader(columns, fxQuoteHeaders)
+	pairHeader := firstHeader(columns, fxPairHeaders)
+	if dateHeader == "" || rateHeader == "" {
+		return nil, 0, nil, errors.New("the file needs a date and a rate column")
+	}
+	if (baseHeader == "" || quoteHeader == "") && pairHeader == "" {
+		return nil, 0, nil, errors.New("the file needs base and quote columns or a pair column")
+	}
+
+	var rates []models.FXRate
+	var rowErrors []RowError
+	rows := records[1:]
+	for i, record := range rows {
+		rowNumber := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+
+		rate := models.FXRate{UserID: userID}
+		if baseHeader != "" && quoteHeader != "" {
+			rate.BaseCurrency = columns.get(record, baseHeader)
+			rate.QuoteCurrency = columns.get(record, quoteHeader)
+		} else {
+			rate.BaseCurrency, rate.QuoteCurrency = splitCurrencyPair(columns.get(record, pairHeader))
+		}
+		asOf, err := parseFXDate(columns.get(record, dateHeader))
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		rate.AsOf = asOf
+		rate.Rate, err = parseNumber(columns.get(record, rateHeader))
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Error: "invalid rate: " + err.Error()})
+			continue
+		}
+		if err := models.ValidateFXRate(&rate); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		rates = append(rates, rate)
+	}
+	return rates, len(rows), rowErrors, nil
+}
+
+// ImportFXRates parses the csv and saves every rate in one transaction, so a file either
+// goes in completely or not at all. rows that couldn't be parsed are only reported
+func ImportFXRates(db *sql.DB, userID int, r io.Reader) (FXImportReport, error) {
+	report := FXImportReport{Errors: []RowError{}}
+	rates, totalRows, rowErrors, err := ParseFXRatesCSV(r, userID)
+	if err != nil {
+		return report, err
+	}
+	report.TotalRows = totalRows
+	report.Errors = append(report.Errors, rowErrors...)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range rates {
+		if err := models.SaveFXRate(tx, &rates[i]); err != nil {
+			return report, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit fx rates: %w", err)
+	}
+
+	report.ImportedCount = len(rates)
+	report.FailedCount = len(report.Errors)
+	return report, nil
+}
+
+func firstHeader(columns columnIndex, names []string) string {
+	for _, name := range names {
+		if columns.has(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// EURUSD, EUR/USD, EUR-USD and EUR.USD all give EUR and USD
+func splitCurrencyPair(pair string) (string, string) {
+	pair = strings.ToUpper(strings.TrimSpace(pair))
+	pair = strings.TrimSuffix(pair, "=X") // yahoo finance style, EURUSD=X
+	for _, separator := range []string{"/", "-", "."} {
+		if base, quote, ok := strings.Cut(pair, separator); ok {
+			return base, quote
+		}
+	}
+	if len(pair) == 6 {
+		return pair[:3], pair[3:]
+	}
+	return pair, ""
+}
+
+// rate files are usually daily, so plain dates are accepted on top of the trade timestamps
+func parseFXDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := parseTimestamp(s, time.UTC); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
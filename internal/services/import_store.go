@@ -0,0 +1,248 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"trading-journal/internal/models"
+)
+
+// ImportReport is what gets sent back to the client after an import is saved
+type ImportReport struct {
+	Format        string          `json:"format"`
+	TotalRows     int             `json:"total_rows"`
+	ImportedCount int             `json:"imported_count"`
+	SkippedCount  int             `json:"skipped_count"`
+	UpdatedCount  int             `json:"updated_count"`
+	FailedCount   int             `json:"failed_count"`
+	TradeIDs      []int           `json:"trade_ids"`
+	Duplicates    []DuplicateRow  `json:"duplicates"`
+	Errors        []RowError      `json:"errors"`
+	Warnings      []ImportWarning `json:"warnings"`
+}
+
+// DuplicateRow is a row that matched a trade that was already in the journal
+type DuplicateRow struct {
+	Row     int             `json:"row"`
+	TradeID int             `json:"trade_id"`
+	Action  DuplicateAction `json:"action"`
+}
+
+// SaveImportedTrades inserts every parsed trade and its metrics in a single transaction.
+// each trade gets its own savepoint, so one bad row is reported as a failure without
+// rolling back the rest of the file.
+// every trade is fingerprinted with the source and broker account, rows that match a trade
+// that's already there are skipped or update that trade depending on options.OnDuplicate.
+// trades built from fills are saved along with their executions, linked to the trade
+func SaveImportedTrades(db *sql.DB, userID int, source string, result ImportResult, options ImportOptions) (ImportReport, error) {
+	report := ImportReport{
+		Format:     result.Format,
+		TotalRows:  result.TotalRows,
+		TradeIDs:   []int{},
+		Duplicates: []DuplicateRow{},
+		Errors:     append([]RowError{}, result.Errors...),
+		Warnings:   append([]ImportWarning{}, result.Warnings...),
+	}
+	onDuplicate := options.OnDuplicate
+	if onDuplicate == "" {
+		onDuplicate = SkipDuplicates
+	}
+	if onDuplicate != SkipDuplicates && onDuplicate != UpdateDuplicates {
+		return report, fmt.Errorf("unknown duplicate action %q, use skip or update", onDuplicate)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("failed to start import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	accounts := importAccounts{userID: userID, source: source, ids: make(map[string]int)}
+	if options.AccountID != nil {
+		if _, err := models.GetAccount(tx, userID, *options.AccountID); err != nil {
+			return report, err
+		}
+		accounts.fixedID = *options.AccountID
+	}
+
+	for _, imported := range result.Trades {
+		trade := imported.Trade
+		trade.UserID = userID
+		if trade.AccountID, err = accounts.resolve(tx, imported.Account); err != nil {
+			return report, err
+		}
+		fingerprint := TradeFingerprint(source, imported.Account, trade)
+		trade.Fingerprint = &fingerprint
+		trade.Source = &source
+		if imported.Account != "" {
+			account := imported.Account
+			trade.BrokerAccount = &account
+		}
+
+		existingID, found, err := models.FindTradeIDByFingerprint(tx, userID, fingerprint)
+		if err != nil {
+			return report, err
+		}
+		if legacy, ok := legacyTradeFingerprint(source, imported.Account, trade); ok && !found {
+			if existingID, found, err = models.FindTradeIDByFingerprint(tx, userID, legacy); err != nil {
+				return report, err
+			}
+		}
+		if found {
+			if onDuplicate == UpdateDuplicates {
+				if err := updateImportedTrade(tx, userID, existingID, trade, imported.Executions); err != nil {
+					log.Printf("Error updating trade %d from row %d: %v", existingID, imported.Row, err)
+					report.Errors = append(report.Errors, RowError{Row: imported.Row, Error: err.Error()})
+					continue
+				}
+				report.UpdatedCount++
+			} else {
+				report.SkippedCount++
+			}
+			report.Duplicates = append(report.Duplicates, DuplicateRow{Row: imported.Row, TradeID: existingID, Action: onDuplicate})
+			continue
+		}
+
+		id, err := saveImportedTrade(tx, trade, imported.Executions)
+		if err != nil {
+			log.Printf("Error importing row %d: %v", imported.Row, err)
+			report.Errors = append(report.Errors, RowError{Row: imported.Row, Error: err.Error()})
+			continue
+		}
+		report.TradeIDs = append(report.TradeIDs, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit import transaction: %w", err)
+	}
+
+	report.ImportedCount = len(report.TradeIDs)
+	report.FailedCount = len(report.Errors)
+	return report, nil
+}
+
+// importAccounts works out which journal account each imported trade goes into
+type importAccounts struct {
+	userID  int
+	source  string
+	fixedID int
+	ids     map[string]int
+}
+
+func (a *importAccounts) resolve(tx *sql.Tx, brokerAccount string) (int, error) {
+	if a.fixedID != 0 {
+		return a.fixedID, nil
+	}
+	if id, ok := a.ids[brokerAccount]; ok {
+		return id, nil
+	}
+
+	name := brokerAccount
+	var broker *string
+	if name == "" {
+		name = models.DefaultAccountName
+	} else {
+		broker = &a.source
+	}
+	id, err := models.GetOrCreateAccountByName(tx, a.userID, name, broker)
+	if err != nil {
+		return 0, err
+	}
+	a.ids[brokerAccount] = id
+	return id, nil
+}
+
+func saveImportedTrade(tx *sql.Tx, trade models.Trade, executions []models.Execution) (int, error) {
+	var id int
+	err := withSavepoint(tx, func() error {
+		var err error
+		id, err = models.AddTrade(tx, trade)
+		if err != nil {
+			return err
+		}
+		trade.ID = id
+		if err := models.CalculateAndInsertTradeMetrics(tx, trade); err != nil {
+			return err
+		}
+		return saveImportedExecutions(tx, trade, executions)
+	})
+	return id, err
+}
+
+// saveImportedExecutions stores the fills a trade was built from, in the trade's account
+func saveImportedExecutions(tx *sql.Tx, trade models.Trade, executions []models.Execution) error {
+	pieces := make([]models.Execution, len(executions))
+	for i, execution := range executions {
+		execution.AccountID = trade.AccountID
+		pieces[i] = execution
+	}
+	return saveExecutionPieces(tx, trade.UserID, pieces, &trade.ID)
+}
+
+// overwrite what the broker knows about an existing trade, but keep anything the user
+// added by hand in the journal (notes, screenshot, stop and target, planned risk, strategy) if the
+// statement doesn't have it. the fills of a trade built from fills replace the ones it had
+func updateImportedTrade(tx *sql.Tx, userID, id int, imported models.Trade, executions []models.Execution) error {
+	return withSavepoint(tx, func() error {
+		existing, err := models.GetTrade(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		imported.ID = id
+		if imported.Notes == nil {
+			imported.Notes = existing.Notes
+		}
+		if imported.ScreenshotURL == nil {
+			imported.ScreenshotURL = existing.ScreenshotURL
+		}
+		if imported.StopLoss == nil {
+			imported.StopLoss = existing.StopLoss
+		}
+		if imported.TakeProfit == nil {
+			imported.TakeProfit = existing.TakeProfit
+		}
+		if imported.PlannedRisk == nil && imported.PlannedRiskTicks == nil {
+			imported.PlannedRisk = existing.PlannedRisk
+			imported.PlannedRiskTicks = existing.PlannedRiskTicks
+		}
+		if imported.StrategyID == nil {
+			imported.StrategyID = existing.StrategyID
+		}
+
+		if err := models.UpdateTrade(tx, userID, imported); err != nil {
+			return err
+		}
+		if err := models.CalculateAndInsertTradeMetrics(tx, imported); err != nil {
+			return err
+		}
+		if len(executions) == 0 {
+			return nil
+		}
+		if err := models.DeleteTradeExecutions(tx, userID, id); err != nil {
+			return err
+		}
+		return saveImportedExecutions(tx, imported, executions)
+	})
+}
+
+// run fn inside a savepoint, so if it fails only its changes are undone and the
+// transaction can keep going
+func withSavepoint(tx *sql.Tx, fn func() error) error {
+	if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rollbackErr != nil {
+			return fmt.Errorf("failed to roll back savepoint: %w", rollbackErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec("RELEASE SAVEPOINT import_row"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Importer turns a broker statement into normalized trades. rows that can't be used
+// go into the result's errors, anything that was imported but looks off goes into warnings
+type Importer interface {
+	Import(r io.Reader, options ImportOptions) (ImportResult, error)
+}
+
+// ImportOptions are sent along with the file, importers ignore whatever they don't need
+type ImportOptions struct {
+	// maps a trade field (e.g. "entry_price") to the header used for it in the file
+	Columns map[string]string `json:"columns"`
+	// go time layout for the timestamps in the file, the common ones are tried when empty
+	TimeLayout string `json:"time_layout"`
+	// IANA timezone of the timestamps in the file that have no offset, brokers usually write
+	// them in the trader's local time. the import handler defaults it to the user's timezone,
+	// it's UTC when empty
+	Timezone string `json:"timezone"`
+	// what to do with trades that were already imported, defaults to skipping them
+	OnDuplicate DuplicateAction `json:"on_duplicate"`
+	// journal account to put every trade in. when it's not set trades go into an account
+	// named after the broker account in the file, or the default account if there isn't one
+	AccountID *int `json:"account_id"`
+}
+
+// location is where timestamps without an offset are read in
+func (o ImportOptions) location() (*time.Location, error) {
+	timezone := strings.TrimSpace(o.Timezone)
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", o.Timezone)
+	}
+	return location, nil
+}
+
+// DuplicateAction decides what happens to a row whose fingerprint is already in the journal
+type DuplicateAction string
+
+const (
+	SkipDuplicates   DuplicateAction = "skip"
+	UpdateDuplicates DuplicateAction = "update"
+)
+
+// ImportWarning flags something about a row that was imported anyway
+type ImportWarning struct {
+	Row     int    `json:"row,omitempty"`
+	Message string `json:"message"`
+}
+
+// registry of importers keyed by the format name used in /api/imports/{format}
+var importers = map[string]Importer{}
+
+func RegisterImporter(format string, importer Importer) {
+	importers[strings.ToLower(format)] = importer
+}
+
+func GetImporter(format string) (Importer, bool) {
+	importer, ok := importers[strings.ToLower(format)]
+	return importer, ok
+}
+
+// ImportFormats lists every registered format name
+func ImportFormats() []string {
+	formats := make([]string, 0, len(importers))
+	for format := range importers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func init() {
+	RegisterImporter("ninjatrader", NewNinjaTraderImporterService())
+	RegisterImporter("csv", NewColumnMappedCSVImporter())
+}
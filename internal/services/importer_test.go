@@ -0,0 +1,80 @@
+package services
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetImporter(t *testing.T) {
+	tests := []struct {
+		format string
+		want   Importer
+	}{
+		{"ninjatrader", &NinjaTraderImporterService{}},
+		{"NinjaTrader", &NinjaTraderImporterService{}},
+		{"csv", &ColumnMappedCSVImporter{}},
+		{" CSV", nil},
+		{"ibkr", nil},
+	}
+	for _, tt := range tests {
+		importer, ok := GetImporter(tt.format)
+		if ok != (tt.want != nil) {
+			t.Errorf("GetImporter(%q) found = %v, want %v", tt.format, ok, tt.want != nil)
+			continue
+		}
+		if ok && reflect.TypeOf(importer) != reflect.TypeOf(tt.want) {
+			t.Errorf("GetImporter(%q) = %T, want %T", tt.format, importer, tt.want)
+		}
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	want := []string{"csv", "ninjatrader"}
+	if got := ImportFormats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportFormats() = %v, want %v", got, want)
+	}
+}
+
+// helpers shared by the importer tests
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatalf("bad time in test: %v", err)
+	}
+	return parsed
+}
+
+func importedRows(trades []ImportedTrade) []int {
+	rows := []int{}
+	for _, trade := range trades {
+		rows = append(rows, trade.Row)
+	}
+	return rows
+}
+
+func errorRows(errors []RowError) []int {
+	rows := []int{}
+	for _, e := range errors {
+		rows = append(rows, e.Row)
+	}
+	return rows
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// NinjaTrader can export either a "Trades" grid (one row per round trip) or an
+// "Executions" grid (one row per fill), these are the two layouts we understand
+const (
+	NinjaTraderTradesFormat     = "trades"
+	NinjaTraderExecutionsFormat = "executions"
+)
+
+// RowError describes a single csv row that could not be turned into a trade
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportedTrade is a parsed trade along with the csv row it came from, so
+// failures when saving can still be reported against the original file
+type ImportedTrade struct {
+	Row     int          `json:"row"`
+	Account string       `json:"account"`
+	Trade   models.Trade `json:"trade"`
+	// the fills the trade was built from, only exports with one row per fill have them
+	Executions []models.Execution `json:"-"`
+}
+
+type ImportResult struct {
+	Format    string          `json:"format"`
+	TotalRows int             `json:"total_rows"`
+	Trades    []ImportedTrade `json:"trades"`
+	Errors    []RowError      `json:"errors"`
+	Warnings  []ImportWarning `json:"warnings"`
+}
+
+type NinjaTraderImporterService struct{}
+
+func NewNinjaTraderImporterService() *NinjaTraderImporterService {
+	return &NinjaTraderImporterService{}
+}
+
+// Import lets the NinjaTrader importer be used through the importer registry,
+// both export layouts are detected from the header so the timezone is the only option it reads
+func (s *NinjaTraderImporterService) Import(r io.Reader, options ImportOptions) (ImportResult, error) {
+	location, err := options.location()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return s.ImportTrades(r, location)
+}
+
+// ImportTrades reads a NinjaTrader csv export and turns it into trades.
+// rows that can't be parsed are reported in the result instead of failing the whole file.
+// NinjaTrader writes timestamps in the machine's local time without an offset, they're read
+// in location and converted to UTC
+func (s *NinjaTraderImporterService) ImportTrades(r io.Reader, location *time.Location) (ImportResult, error) {
+	var result ImportResult
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // NinjaTrader sometimes leaves a trailing comma on rows
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return result, errors.New("csv file is empty")
+	}
+
+	columns := headerIndex(records[0])
+	rows := records[1:]
+	result.TotalRows = len(rows)
+
+	// figure out which export this is by looking at the header
+	switch {
+	case columns.has("market pos."):
+		result.Format = NinjaTraderTradesFormat
+		s.parseTradesExport(columns, rows, location, &result)
+	case columns.has("action") && columns.has("price"):
+		result.Format = NinjaTraderExecutionsFormat
+		s.parseExecutionsExport(columns, rows, location, &result)
+	default:
+		return result, errors.New("unrecognized NinjaTrader export, expected a Trades or Executions csv")
+	}
+
+	return result, nil
+}
+
+// parse the Trades export, where every row is already a complete round trip
+func (s *NinjaTraderImporterService) parseTradesExport(columns columnIndex, rows [][]string, location *time.Location, result *ImportResult) {
+	for i, record := range rows {
+		// +2 because of the header and because rows are 1-indexed in a spreadsheet
+		rowNumber := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+
+		trade, err := s.tradeFromTradesRow(columns, record, location)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		result.Trades = append(result.Trades, ImportedTrade{Row: rowNumber, Account: columns.get(record, "account"), Trade: trade})
+	}
+}
+
+func (s *NinjaTraderImporterService) tradeFromTradesRow(columns columnIndex, record []string, location *time.Location) (models.Trade, error) {
+	var trade models.Trade
+
+	instrument := columns.get(record, "instrument")
+	if instrument == "" {
+		return trade, errors.New("missing instrument")
+	}
+	trade.Ticker = strings.ToUpper(instrument)
+
+	switch strings.ToUpper(columns.get(record, "market pos.")) {
+	case "LONG":
+		trade.Direction = "LONG"
+	case "SHORT":
+		trade.Direction = "SHORT"
+	default:
+		return trade, fmt.Errorf("invalid market position %q", columns.get(record, "market pos."))
+	}
+
+	var err error
+	if trade.Quantity, err = parseNumber(columns.get(record, "qty")); err != nil {
+		return trade, fmt.Errorf("invalid qty: %w", err)
+	}
+	if trade.EntryPrice, err = parseNumber(columns.get(record, "entry price")); err != nil {
+		return trade, fmt.Errorf("invalid entry price: %w", err)
+	}
+	exitPrice, err := parseNumber(columns.get(record, "exit price"))
+	if err != nil {
+		return trade, fmt.Errorf("invalid exit price: %w", err)
+	}
+	trade.ExitPrice = &exitPrice
+	if trade.EntryTime, err = parseTimestamp(columns.get(record, "entry time"), location); err != nil {
+		return trade, fmt.Errorf("invalid entry time: %w", err)
+	}
+	exitTime, err := parseTimestamp(columns.get(record, "exit time"), location)
+	if err != nil {
+		return trade, fmt.Errorf("invalid exit time: %w", err)
+	}
+	trade.ExitTime = &exitTime
+	trade.TradeDate = trade.EntryTime
+
+	// the fee columns are optional and depend on the NinjaTrader version
+	var commissions float64
+	for _, column := range []string{"commission", "clearing fee", "exchange fee", "ip fee", "nfa fee"} {
+		if value := columns.get(record, column); value != "" {
+			fee, err := parseNumber(value)
+			if err != nil {
+				return trade, fmt.Errorf("invalid %s: %w", column, err)
+			}
+			commissions += math.Abs(fee)
+		}
+	}
+	trade.Commissions = &commissions
+
+	return trade, validateImportedTrade(trade)
+}
+
+// a single fill from the Executions export, along with where it came from
+type ninjaTraderFill struct {
+	row       int
+	account   string
+	execution models.Execution
+}
+
+// parse the Executions export, where each row is a fill. fills are grouped into
+// round trips per account and instrument by the position engine
+func (s *NinjaTraderImporterService) parseExecutionsExport(columns columnIndex, rows [][]string, location *time.Location, result *ImportResult) {
+	// fills are matched separately for each account, keep the accounts in the order we first see them
+	var accounts []string
+	fillsByAccount := make(map[string][]ninjaTraderFill)
+	for i, record := range rows {
+		rowNumber := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+
+		fill, err := s.fillFromExecutionsRow(columns, record, location)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		fill.row = rowNumber
+		if _, ok := fillsByAccount[fill.account]; !ok {
+			accounts = append(accounts, fill.account)
+		}
+		fillsByAccount[fill.account] = append(fillsByAccount[fill.account], fill)
+	}
+
+	for _, account := range accounts {
+		fills := fillsByAccount[account]
+		executions := make([]models.Execution, len(fills))
+		for i, fill := range fills {
+			executions[i] = fill.execution
+		}
+
+		trips, positions := BuildRoundTrips(executions, AverageCostMatching)
+		for _, trip := range trips {
+			trade := trip.Trade
+			// the trade keeps the full contract, AddTrade fills in its root symbol
+			result.Trades = append(result.Trades, ImportedTrade{
+				Row:        fills[trip.FirstIndex].row,
+				Account:    account,
+				Trade:      trade,
+				Executions: trip.Executions,
+			})
+		}
+
+		// anything left over never got back to flat inside this file
+		for _, position := range positions {
+			result.Errors = append(result.Errors, RowError{
+				Row:   fills[position.FirstIndex].row,
+				Error: "position is still open at the end of the file",
+			})
+		}
+	}
+
+	sort.SliceStable(result.Trades, func(i, j int) bool {
+		return result.Trades[i].Row < result.Trades[j].Row
+	})
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+}
+
+func (s *NinjaTraderImporterService) fillFromExecutionsRow(columns columnIndex, record []string, location *time.Location) (ninjaTraderFill, error) {
+	var fill ninjaTraderFill
+	execution := &fill.execution
+
+	execution.Ticker = columns.get(record, "instrument")
+	if execution.Ticker == "" {
+		return fill, errors.New("missing instrument")
+	}
+	fill.account = columns.get(record, "account")
+
+	var err error
+	if execution.Quantity, err = parseNumber(columns.get(record, "quantity")); err != nil {
+		return fill, fmt.Errorf("invalid quantity: %w", err)
+	}
+	if execution.Quantity <= 0 {
+		return fill, errors.New("quantity must be greater than 0")
+	}
+
+	// actions look like "Buy", "Sell", "Buy to cover" or "Sell short"
+	action := strings.ToUpper(columns.get(record, "action"))
+	switch {
+	case strings.HasPrefix(action, "BUY"):
+		execution.Side = "BUY"
+	case strings.HasPrefix(action, "SELL"):
+		execution.Side = "SELL"
+	default:
+		return fill, fmt.Errorf("invalid action %q", columns.get(record, "action"))
+	}
+
+	if execution.Price, err = parseNumber(columns.get(record, "price")); err != nil {
+		return fill, fmt.Errorf("invalid price: %w", err)
+	}
+	if execution.ExecutedAt, err = parseTimestamp(columns.get(record, "time"), location); err != nil {
+		return fill, fmt.Errorf("invalid time: %w", err)
+	}
+	if value := columns.get(record, "commission"); value != "" {
+		commission, err := parseNumber(value)
+		if err != nil {
+			return fill, fmt.Errorf("invalid commission: %w", err)
+		}
+		execution.Fees = math.Abs(commission)
+	}
+
+	return fill, nil
+}
+
+// make sure the trade will pass the checks on the trades table before we try to insert it
+func validateImportedTrade(trade models.Trade) error {
+	if trade.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	// statements only list round trips, so every imported trade is closed
+	if trade.ExitPrice == nil || trade.ExitTime == nil {
+		return errors.New("missing exit price or exit time")
+	}
+	if trade.EntryPrice <= 0 || *trade.ExitPrice <= 0 {
+		return errors.New("entry and exit prices must be greater than 0")
+	}
+	if trade.ExitTime.Before(trade.EntryTime) {
+		return errors.New("exit time is before entry time")
+	}
+	return nil
+}
+
+// columnIndex maps a lowercased header name to its position in the row
+type columnIndex map[string]int
+
+func headerIndex(header []string) columnIndex {
+	columns := make(columnIndex, len(header))
+	for i, name := range header {
+		// strip the utf-8 byte order mark excel likes to add to the first column
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func (c columnIndex) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+func (c columnIndex) get(record []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNumber handles the currency formatting NinjaTrader uses in its exports,
+// e.g. "$1,234.50", "-$12.50" or "($12.50)" for negatives
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("value is empty")
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.NewReplacer("$", "", ",", "", " ", "").Replace(s)
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		f = -f
+	}
+	return f, nil
+}
+
+// layouts NinjaTrader uses for timestamps depending on the machine's locale
+var timestampLayouts = []string{
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"02.01.2006 15:04:05",
+	time.RFC3339,
+}
+
+// parseTimestamp reads a timestamp in any of the layouts above. one without an offset is taken
+// to be in location, the result is always in UTC
+func parseTimestamp(s string, location *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+}
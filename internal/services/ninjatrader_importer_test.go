@@ -0,0 +1,264 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNinjaTraderTradesExport(t *testing.T) {
+	result, err := NewNinjaTraderImporterService().ImportTrades(openFixture(t, "ninjatrader_trades.csv"), time.UTC)
+	if err != nil {
+		t.Fatalf("ImportTrades() error = %v", err)
+	}
+
+	if result.Format != NinjaTraderTradesFormat {
+		t.Errorf("Format = %q, want %q", result.Format, NinjaTraderTradesFormat)
+	}
+	if result.TotalRows != 8 {
+		t.Errorf("TotalRows = %d, want 8", result.TotalRows)
+	}
+	if got := importedRows(result.Trades); !reflect.DeepEqual(got, []int{2, 3, 7, 8}) {
+		t.Fatalf("imported rows = %v, want [2 3 7 8]", got)
+	}
+
+	tests := []struct {
+		row         int
+		account     string
+		ticker      string
+		direction   string
+		quantity    float64
+		entryPrice  float64
+		exitPrice   float64
+		entryTime   string
+		exitTime    string
+		commissions float64
+	}{
+		// thousands separators, 12 hour clock, commission and exchange fee added up
+		{2, "Sim101", "ESM5", "LONG", 2, 5210.25, 5214.50, "2025-05-01T09:31:05Z", "2025-05-01T09:45:10Z", 6.12},
+		// a negative fee in parentheses is still a cost
+		{3, "Sim101", "NQM5", "SHORT", 1, 18250, 18270.5, "2025-05-01T10:02:00Z", "2025-05-01T10:20:30Z", 2.06},
+		// iso timestamps
+		{7, "Live1", "CLN5", "LONG", 3, 61.52, 61.87, "2025-05-02T14:00:00Z", "2025-05-02T14:30:00Z", 6.18},
+		// european timestamps and sub-cent prices
+		{8, "Live1", "6EM5", "SHORT", 1, 1.13455, 1.13405, "2025-05-02T15:00:00Z", "2025-05-02T15:10:00Z", 2.06},
+	}
+	for i, tt := range tests {
+		imported := result.Trades[i]
+		trade := imported.Trade
+		if imported.Row != tt.row || imported.Account != tt.account {
+			t.Errorf("trade %d: row %d account %q, want row %d account %q", i, imported.Row, imported.Account, tt.row, tt.account)
+		}
+		if trade.Ticker != tt.ticker || trade.Direction != tt.direction {
+			t.Errorf("row %d: %s %s, want %s %s", tt.row, trade.Ticker, trade.Direction, tt.ticker, tt.direction)
+		}
+		if !approxEqual(trade.Quantity, tt.quantity) || !approxEqual(trade.EntryPrice, tt.entryPrice) ||
+			trade.ExitPrice == nil || !approxEqual(*trade.ExitPrice, tt.exitPrice) {
+			t.Errorf("row %d: quantity %v entry %v exit %v, want %v %v %v", tt.row, trade.Quantity, trade.EntryPrice,
+				trade.ExitPrice, tt.quantity, tt.entryPrice, tt.exitPrice)
+		}
+		if !trade.EntryTime.Equal(mustTime(t, tt.entryTime)) || trade.ExitTime == nil || !trade.ExitTime.Equal(mustTime(t, tt.exitTime)) {
+			t.Errorf("row %d: entry %v exit %v, want %s %s", tt.row, trade.EntryTime, trade.ExitTime, tt.entryTime, tt.exitTime)
+		}
+		if trade.Commissions == nil || !approxEqual(*trade.Commissions, tt.commissions) {
+			t.Errorf("row %d: commissions %v, want %v", tt.row, trade.Commissions, tt.commissions)
+		}
+		if len(imported.Executions) != 0 {
+			t.Errorf("row %d: a trades export has no fills, got %d", tt.row, len(imported.Executions))
+		}
+	}
+
+	wantErrors := []struct {
+		row     int
+		message string
+	}{
+		{4, "invalid market position"},
+		{5, "invalid entry time"},
+		{9, "exit time is before entry time"},
+	}
+	if got := errorRows(result.Errors); !reflect.DeepEqual(got, []int{4, 5, 9}) {
+		t.Fatalf("error rows = %v, want [4 5 9]", got)
+	}
+	for i, want := range wantErrors {
+		if !strings.Contains(result.Errors[i].Error, want.message) {
+			t.Errorf("row %d error = %q, want it to mention %q", want.row, result.Errors[i].Error, want.message)
+		}
+	}
+}
+
+func TestNinjaTraderExecutionsExport(t *testing.T) {
+	result, err := NewNinjaTraderImporterService().ImportTrades(openFixture(t, "ninjatrader_executions.csv"), time.UTC)
+	if err != nil {
+		t.Fatalf("ImportTrades() error = %v", err)
+	}
+
+	if result.Format != NinjaTraderExecutionsFormat {
+		t.Errorf("Format = %q, want %q", result.Format, NinjaTraderExecutionsFormat)
+	}
+	if result.TotalRows != 11 {
+		t.Errorf("TotalRows = %d, want 11", result.TotalRows)
+	}
+	if got := importedRows(result.Trades); !reflect.DeepEqual(got, []int{2, 4, 9, 10}) {
+		t.Fatalf("imported rows = %v, want [2 4 9 10]", got)
+	}
+
+	tests := []struct {
+		row         int
+		account     string
+		ticker      string
+		direction   string
+		quantity    float64
+		entryPrice  float64
+		exitPrice   float64
+		exitTime    string
+		commissions float64
+		fills       int
+	}{
+		// scaled out in two fills, the exit is their average
+		{2, "Sim101", "ESM5", "LONG", 2, 5210.25, 5213.25, "2025-05-01T09:45:10Z", 4.12, 3},
+		{4, "Sim101", "NQM5", "SHORT", 1, 18250, 18270.5, "2025-05-01T10:20:30Z", 2.06, 2},
+		// the sell of row 10 closes the long and opens a short, its fee is split between them
+		{9, "Live1", "ESM5", "LONG", 1, 5200, 5202, "2025-05-01T11:10:00Z", 2.06, 2},
+		{10, "Live1", "ESM5", "SHORT", 1, 5202, 5201, "2025-05-01T11:20:00Z", 2.06, 2},
+	}
+	for i, tt := range tests {
+		imported := result.Trades[i]
+		trade := imported.Trade
+		if imported.Row != tt.row || imported.Account != tt.account {
+			t.Errorf("trade %d: row %d account %q, want row %d account %q", i, imported.Row, imported.Account, tt.row, tt.account)
+		}
+		if trade.Ticker != tt.ticker || trade.Direction != tt.direction {
+			t.Errorf("row %d: %s %s, want %s %s", tt.row, trade.Ticker, trade.Direction, tt.ticker, tt.direction)
+		}
+		if !approxEqual(trade.Quantity, tt.quantity) || !approxEqual(trade.EntryPrice, tt.entryPrice) ||
+			trade.ExitPrice == nil || !approxEqual(*trade.ExitPrice, tt.exitPrice) {
+			t.Errorf("row %d: quantity %v entry %v exit %v, want %v %v %v", tt.row, trade.Quantity, trade.EntryPrice,
+				trade.ExitPrice, tt.quantity, tt.entryPrice, tt.exitPrice)
+		}
+		if trade.ExitTime == nil || !trade.ExitTime.Equal(mustTime(t, tt.exitTime)) {
+			t.Errorf("row %d: exit %v, want %s", tt.row, trade.ExitTime, tt.exitTime)
+		}
+		if trade.Commissions == nil || !approxEqual(*trade.Commissions, tt.commissions) {
+			t.Errorf("row %d: commissions %v, want %v", tt.row, trade.Commissions, tt.commissions)
+		}
+		// the fills are kept so they can be saved with the trade
+		if len(imported.Executions) != tt.fills {
+			t.Errorf("row %d: %d fills, want %d", tt.row, len(imported.Executions), tt.fills)
+		}
+		var filled float64
+		for _, e := range imported.Executions {
+			if e.Side == "BUY" {
+				filled += e.Quantity
+			}
+		}
+		if !approxEqual(filled, tt.quantity) {
+			t.Errorf("row %d: bought %v in the fills, want %v", tt.row, filled, tt.quantity)
+		}
+	}
+
+	wantErrors := []struct {
+		row     int
+		message string
+	}{
+		{7, "invalid action"},
+		{8, "quantity must be greater than 0"},
+		{12, "still open"},
+	}
+	if got := errorRows(result.Errors); !reflect.DeepEqual(got, []int{7, 8, 12}) {
+		t.Fatalf("error rows = %v, want [7 8 12]", got)
+	}
+	for i, want := range wantErrors {
+		if !strings.Contains(result.Errors[i].Error, want.message) {
+			t.Errorf("row %d error = %q, want it to mention %q", want.row, result.Errors[i].Error, want.message)
+		}
+	}
+}
+
+func TestNinjaTraderTimezone(t *testing.T) {
+	// the export has no offsets, a trader in Chicago opened the first trade at 9:31 CDT
+	result, err := NewNinjaTraderImporterService().Import(openFixture(t, "ninjatrader_trades.csv"),
+		ImportOptions{Timezone: "America/Chicago"})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	trade := result.Trades[0].Trade
+	if want := mustTime(t, "2025-05-01T14:31:05Z"); !trade.EntryTime.Equal(want) || trade.EntryTime.Location() != time.UTC {
+		t.Errorf("EntryTime = %v, want %v", trade.EntryTime, want)
+	}
+	if want := mustTime(t, "2025-05-01T14:45:10Z"); !trade.ExitTime.Equal(want) {
+		t.Errorf("ExitTime = %v, want %v", trade.ExitTime, want)
+	}
+
+	if _, err := NewNinjaTraderImporterService().Import(strings.NewReader(""), ImportOptions{Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("Import() with an unknown timezone error = nil, want one")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// zoneless times are in the given location
+		{"5/1/2025 9:31:05 AM", "2025-05-01T07:31:05Z"},
+		{"01.12.2025 15:00:00", "2025-12-01T14:00:00Z"},
+		// an offset in the timestamp wins over it
+		{"2025-05-01T09:31:05-04:00", "2025-05-01T13:31:05Z"},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in, berlin)
+		if err != nil {
+			t.Errorf("parseTimestamp(%q) error = %v", tt.in, err)
+			continue
+		}
+		if want := mustTime(t, tt.want); !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.in, got, want)
+		}
+	}
+}
+
+func TestNinjaTraderUnrecognizedExport(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"empty", ""},
+		{"other layout", "Date,Symbol,Amount\n2025-05-01,ES,100\n"},
+	}
+	for _, tt := range tests {
+		if _, err := NewNinjaTraderImporterService().ImportTrades(strings.NewReader(tt.csv), time.UTC); err == nil {
+			t.Errorf("%s: ImportTrades() error = nil, want one", tt.name)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"1234.5", 1234.5, false},
+		{"$1,234.50", 1234.5, false},
+		{"-$12.50", -12.5, false},
+		{"($12.50)", -12.5, false},
+		{" 0.00005 ", 0.00005, false},
+		{"", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNumber(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !approxEqual(got, tt.want) {
+			t.Errorf("parseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
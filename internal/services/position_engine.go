@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// MatchingMethod decides which entry fills an exit is matched against
+type MatchingMethod string
+
+const (
+	// FIFOMatching closes the oldest open lots first
+	FIFOMatching MatchingMethod = "fifo"
+	// AverageCostMatching keeps a single lot at the average price of every entry
+	AverageCostMatching MatchingMethod = "average"
+)
+
+func ParseMatchingMethod(s string) (MatchingMethod, error) {
+	switch MatchingMethod(strings.ToLower(s)) {
+	case "", FIFOMatching:
+		return FIFOMatching, nil
+	case AverageCostMatching:
+		return AverageCostMatching, nil
+	default:
+		return "", fmt.Errorf("unknown matching method %q, use fifo or average", s)
+	}
+}
+
+// RoundTrip is a position that went from flat back to flat, along with the fills that made it up
+type RoundTrip struct {
+	Trade      models.Trade       `json:"trade"`
+	Executions []models.Execution `json:"executions"`
+	// index of the opening fill in the slice passed to BuildRoundTrips
+	FirstIndex int `json:"-"`
+}
+
+// OpenPosition is whatever is left over once all the fills have been matched
+type OpenPosition struct {
+	AccountID   int                `json:"account_id"`
+	Ticker      string             `json:"ticker"`
+	Direction   string             `json:"direction"`
+	Quantity    float64            `json:"quantity"`
+	AverageCost float64            `json:"average_cost"`
+	Executions  []models.Execution `json:"executions"`
+	FirstIndex  int                `json:"-"`
+}
+
+// a quantity that was bought (or sold short) at a price and hasn't been closed yet
+type lot struct {
+	quantity float64
+	price    float64
+}
+
+type positionState struct {
+	accountID  int
+	ticker     string
+	direction  string
+	firstIndex int
+	entryTime  time.Time
+	lots       []lot
+	openQty    float64
+	closedQty  float64
+	closedCost float64 // cost basis of the quantity that has been closed
+	exitValue  float64
+	fees       float64
+	executions []models.Execution
+}
+
+// add an entry fill to the position
+func (p *positionState) add(quantity, price float64, method MatchingMethod) {
+	if method == AverageCostMatching && len(p.lots) > 0 {
+		total := p.lots[0].quantity + quantity
+		p.lots[0].price = (p.lots[0].quantity*p.lots[0].price + quantity*price) / total
+		p.lots[0].quantity = total
+	} else {
+		p.lots = append(p.lots, lot{quantity: quantity, price: price})
+	}
+	p.openQty += quantity
+}
+
+// close part of the position and return the cost basis of what was closed
+func (p *positionState) reduce(quantity float64) float64 {
+	var cost float64
+	for quantity > 0 && len(p.lots) > 0 {
+		taken := math.Min(quantity, p.lots[0].quantity)
+		cost += taken * p.lots[0].price
+		p.lots[0].quantity -= taken
+		quantity -= taken
+		if p.lots[0].quantity <= quantityEpsilon {
+			p.lots = p.lots[1:]
+		}
+	}
+	return cost
+}
+
+func (p *positionState) averageCost() float64 {
+	var quantity, value float64
+	for _, l := range p.lots {
+		quantity += l.quantity
+		value += l.quantity * l.price
+	}
+	if quantity == 0 {
+		return 0
+	}
+	return value / quantity
+}
+
+// fills can be fractional (crypto, forex), so don't compare quantities against exactly zero
+const quantityEpsilon = 1e-9
+
+// BuildRoundTrips groups fills by account and ticker into round trip trades. a trade starts when the
+// position leaves flat and ends when it gets back to flat, and its entry and exit prices
+// are the quantity weighted averages of the fills on each side.
+// a fill that takes the position through flat is split in two, the first part closes the
+// trade and the rest opens a new one. the split off part has an ID of 0 since it doesn't
+// exist in the database yet.
+// for a round trip that returns to flat FIFO and average cost give the same prices, the
+// method only changes the cost basis of what's still open at the end
+func BuildRoundTrips(executions []models.Execution, method MatchingMethod) ([]RoundTrip, []OpenPosition) {
+	// process fills in the order they happened without losing track of where they came from
+	order := make([]int, len(executions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return executions[order[i]].ExecutedAt.Before(executions[order[j]].ExecutedAt)
+	})
+
+	var trips []RoundTrip
+	open := make(map[string]*positionState)
+
+	for _, index := range order {
+		execution := executions[index]
+		if execution.Quantity <= 0 {
+			continue
+		}
+		remaining := execution.SignedQuantity()
+		feePerUnit := execution.Fees / execution.Quantity
+		piece := execution
+		key := fmt.Sprintf("%d|%s", execution.AccountID, execution.Ticker)
+
+		for math.Abs(remaining) > quantityEpsilon {
+			pos, ok := open[key]
+			if !ok {
+				// flat, so this fill opens a new position
+				pos = &positionState{
+					accountID:  execution.AccountID,
+					ticker:     execution.Ticker,
+					direction:  "LONG",
+					firstIndex: index,
+					entryTime:  execution.ExecutedAt,
+				}
+				if remaining < 0 {
+					pos.direction = "SHORT"
+				}
+				open[key] = pos
+			}
+
+			var quantity float64
+			if (pos.direction == "LONG") == (remaining > 0) {
+				// adding to the position
+				quantity = math.Abs(remaining)
+				pos.add(quantity, execution.Price, method)
+			} else {
+				// reducing the position, anything past flat is left for the next pass of the loop
+				quantity = math.Min(math.Abs(remaining), pos.openQty)
+				pos.closedCost += pos.reduce(quantity)
+				pos.closedQty += quantity
+				pos.exitValue += quantity * execution.Price
+				pos.openQty -= quantity
+			}
+
+			piece.Quantity = quantity
+			piece.Fees = feePerUnit * quantity
+			pos.fees += piece.Fees
+			pos.executions = append(pos.executions, piece)
+
+			if remaining > 0 {
+				remaining -= quantity
+			} else {
+				remaining += quantity
+			}
+			// anything else from this fill is a new row
+			piece.ID = 0
+
+			if pos.openQty <= quantityEpsilon {
+				trips = append(trips, pos.roundTrip(execution))
+				delete(open, key)
+			}
+		}
+	}
+
+	var positions []OpenPosition
+	for _, pos := range open {
+		positions = append(positions, OpenPosition{
+			AccountID:   pos.accountID,
+			Ticker:      pos.ticker,
+			Direction:   pos.direction,
+			Quantity:    pos.openQty,
+			AverageCost: pos.averageCost(),
+			Executions:  pos.executions,
+			FirstIndex:  pos.firstIndex,
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].FirstIndex < positions[j].FirstIndex
+	})
+
+	return trips, positions
+}
+
+// turn a position that just went flat into a trade
+func (p *positionState) roundTrip(closing models.Execution) RoundTrip {
+	fees := p.fees
+	exitPrice := p.exitValue / p.closedQty
+	exitTime := closing.ExecutedAt
+	trade := models.Trade{
+		UserID:      closing.UserID,
+		AccountID:   p.accountID,
+		Ticker:      p.ticker,
+		Direction:   p.direction,
+		EntryPrice:  p.closedCost / p.closedQty,
+		ExitPrice:   &exitPrice,
+		Status:      models.TradeStatusClosed,
+		Quantity:    p.closedQty,
+		TradeDate:   p.entryTime,
+		EntryTime:   p.entryTime,
+		ExitTime:    &exitTime,
+		Commissions: &fees,
+	}
+	return RoundTrip{Trade: trade, Executions: p.executions, FirstIndex: p.firstIndex}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"trading-journal/internal/models"
+)
+
+// fill is an ES execution in account 1, minute minutes into the session
+func fill(id int, side string, quantity, price, fees float64, minute int) models.Execution {
+	return models.Execution{
+		ID:         id,
+		UserID:     7,
+		AccountID:  1,
+		Ticker:     "ESM5",
+		Side:       side,
+		Quantity:   quantity,
+		Price:      price,
+		Fees:       fees,
+		ExecutedAt: time.Date(2025, 6, 2, 14, 30+minute, 0, 0, time.UTC),
+	}
+}
+
+type wantTrip struct {
+	direction         string
+	quantity          float64
+	entry, exit, fees float64
+	executions        int
+}
+
+type wantPosition struct {
+	direction   string
+	quantity    float64
+	averageCost float64
+}
+
+func TestBuildRoundTrips(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    MatchingMethod
+		fills     []models.Execution
+		trips     []wantTrip
+		positions []wantPosition
+	}{
+		{
+			name:   "long in and out",
+			method: FIFOMatching,
+			fills:  []models.Execution{fill(1, "BUY", 2, 100, 2, 0), fill(2, "SELL", 2, 105, 2, 5)},
+			trips:  []wantTrip{{"LONG", 2, 100, 105, 4, 2}},
+		},
+		{
+			name:   "short in and out",
+			method: FIFOMatching,
+			fills:  []models.Execution{fill(1, "SELL", 2, 50, 1, 0), fill(2, "BUY", 2, 48, 1, 5)},
+			trips:  []wantTrip{{"SHORT", 2, 50, 48, 2, 2}},
+		},
+		{
+			name:   "scaling in at several prices, fifo",
+			method: FIFOMatching,
+			fills: []models.Execution{fill(1, "BUY", 1, 100, 1, 0), fill(2, "BUY", 2, 103, 2, 1),
+				fill(3, "BUY", 1, 106, 1, 2), fill(4, "SELL", 4, 110, 4, 3)},
+			trips: []wantTrip{{"LONG", 4, 103, 110, 8, 4}},
+		},
+		{
+			name:   "scaling in at several prices, average cost",
+			method: AverageCostMatching,
+			fills: []models.Execution{fill(1, "BUY", 1, 100, 1, 0), fill(2, "BUY", 2, 103, 2, 1),
+				fill(3, "BUY", 1, 106, 1, 2), fill(4, "SELL", 4, 110, 4, 3)},
+			trips: []wantTrip{{"LONG", 4, 103, 110, 8, 4}},
+		},
+		{
+			name:   "scaling out",
+			method: FIFOMatching,
+			fills:  []models.Execution{fill(1, "BUY", 3, 100, 0, 0), fill(2, "SELL", 1, 104, 0, 1), fill(3, "SELL", 2, 107, 0, 2)},
+			trips:  []wantTrip{{"LONG", 3, 100, 106, 0, 3}},
+		},
+		{
+			// both methods end up with the same cost basis once the position is flat again
+			name:   "adding after a partial exit, average cost",
+			method: AverageCostMatching,
+			fills: []models.Execution{fill(1, "BUY", 1, 100, 0, 0), fill(2, "BUY", 1, 110, 0, 1), fill(3, "SELL", 1, 120, 0, 2),
+				fill(4, "BUY", 1, 130, 0, 3), fill(5, "SELL", 2, 140, 0, 4)},
+			trips: []wantTrip{{"LONG", 3, 340.0 / 3, 400.0 / 3, 0, 5}},
+		},
+		{
+			// the sell takes the long flat and opens a short with the rest, the fees go with the quantity
+			name:   "a fill that flips the position",
+			method: FIFOMatching,
+			fills:  []models.Execution{fill(1, "BUY", 2, 100, 2, 0), fill(2, "SELL", 5, 110, 5, 1), fill(3, "BUY", 3, 105, 3, 2)},
+			trips:  []wantTrip{{"LONG", 2, 100, 110, 4, 2}, {"SHORT", 3, 110, 105, 6, 2}},
+		},
+		{
+			name:      "left open, fifo",
+			method:    FIFOMatching,
+			fills:     []models.Execution{fill(1, "BUY", 1, 100, 0, 0), fill(2, "BUY", 1, 110, 0, 1), fill(3, "SELL", 1, 120, 0, 2)},
+			positions: []wantPosition{{"LONG", 1, 110}},
+		},
+		{
+			name:      "left open, average cost",
+			method:    AverageCostMatching,
+			fills:     []models.Execution{fill(1, "BUY", 1, 100, 0, 0), fill(2, "BUY", 1, 110, 0, 1), fill(3, "SELL", 1, 120, 0, 2)},
+			positions: []wantPosition{{"LONG", 1, 105}},
+		},
+		{
+			name:   "fills out of order",
+			method: FIFOMatching,
+			fills:  []models.Execution{fill(2, "SELL", 1, 105, 0, 5), fill(1, "BUY", 1, 100, 0, 0)},
+			trips:  []wantTrip{{"LONG", 1, 100, 105, 0, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		trips, positions := BuildRoundTrips(tt.fills, tt.method)
+
+		if len(trips) != len(tt.trips) {
+			t.Errorf("%s: %d round trips, want %d", tt.name, len(trips), len(tt.trips))
+			continue
+		}
+		for i, want := range tt.trips {
+			trade := trips[i].Trade
+			if trade.Direction != want.direction || !approxEqual(trade.Quantity, want.quantity) ||
+				!approxEqual(trade.EntryPrice, want.entry) || !approxEqual(*trade.ExitPrice, want.exit) ||
+				!approxEqual(*trade.Commissions, want.fees) || len(trips[i].Executions) != want.executions {
+				t.Errorf("%s: trip %d = %s %v at %v to %v with %v in fees over %d fills, want %+v", tt.name, i,
+					trade.Direction, trade.Quantity, trade.EntryPrice, *trade.ExitPrice, *trade.Commissions,
+					len(trips[i].Executions), want)
+			}
+			if trade.Status != models.TradeStatusClosed || trade.UserID != 7 || trade.AccountID != 1 {
+				t.Errorf("%s: trip %d is %s for user %d in account %d", tt.name, i, trade.Status, trade.UserID, trade.AccountID)
+			}
+		}
+
+		if len(positions) != len(tt.positions) {
+			t.Errorf("%s: %d open positions, want %d", tt.name, len(positions), len(tt.positions))
+			continue
+		}
+		for i, want := range tt.positions {
+			pos := positions[i]
+			if pos.Direction != want.direction || !approxEqual(pos.Quantity, want.quantity) || !approxEqual(pos.AverageCost, want.averageCost) {
+				t.Errorf("%s: position %d = %s %v at %v, want %+v", tt.name, i, pos.Direction, pos.Quantity, pos.AverageCost, want)
+			}
+		}
+	}
+}
+
+// the part of a flipping fill that opens the new position is a new row, the original keeps its ID
+func TestBuildRoundTripsSplitsFlippingFill(t *testing.T) {
+	fills := []models.Execution{fill(1, "BUY", 2, 100, 2, 0), fill(2, "SELL", 5, 110, 5, 1)}
+	trips, positions := BuildRoundTrips(fills, FIFOMatching)
+	if len(trips) != 1 || len(positions) != 1 {
+		t.Fatalf("%d round trips and %d open positions, want 1 and 1", len(trips), len(positions))
+	}
+
+	closing := trips[0].Executions[1]
+	if closing.ID != 2 || closing.Quantity != 2 || !approxEqual(closing.Fees, 2) {
+		t.Errorf("closing part = ID %d, %v with %v in fees, want ID 2, 2 with 2", closing.ID, closing.Quantity, closing.Fees)
+	}
+	opening := positions[0].Executions[0]
+	if opening.ID != 0 || opening.Quantity != 3 || !approxEqual(opening.Fees, 3) || opening.Side != "SELL" {
+		t.Errorf("opening part = ID %d, %s %v with %v in fees, want ID 0, SELL 3 with 3", opening.ID, opening.Side,
+			opening.Quantity, opening.Fees)
+	}
+	if positions[0].Direction != "SHORT" || positions[0].FirstIndex != 1 {
+		t.Errorf("open position = %s from fill %d, want SHORT from fill 1", positions[0].Direction, positions[0].FirstIndex)
+	}
+}
+
+// positions are kept apart by account and ticker
+func TestBuildRoundTripsSeparatesAccountsAndTickers(t *testing.T) {
+	otherAccount := fill(3, "BUY", 1, 100, 0, 1)
+	otherAccount.AccountID = 2
+	otherTicker := fill(4, "SELL", 1, 20000, 0, 2)
+	otherTicker.Ticker = "NQM5"
+	fills := []models.Execution{fill(1, "BUY", 1, 100, 0, 0), otherAccount, otherTicker, fill(2, "SELL", 1, 102, 0, 3)}
+
+	trips, positions := BuildRoundTrips(fills, FIFOMatching)
+	if len(trips) != 1 || trips[0].Trade.AccountID != 1 || trips[0].Trade.Ticker != "ESM5" {
+		t.Fatalf("round trips = %+v, want the ESM5 trade in account 1", trips)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("%d open positions, want 2", len(positions))
+	}
+	if positions[0].AccountID != 2 || positions[0].Direction != "LONG" {
+		t.Errorf("first open position = %s in account %d, want LONG in account 2", positions[0].Direction, positions[0].AccountID)
+	}
+	if positions[1].Ticker != "NQM5" || positions[1].Direction != "SHORT" {
+		t.Errorf("second open position = %s %s, want SHORT NQM5", positions[1].Direction, positions[1].Ticker)
+	}
+}